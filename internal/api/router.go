@@ -1,9 +1,16 @@
 package api
 
 import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/global"
 	"github.com/l-jessie/test-im/internal/handle"
+	"github.com/l-jessie/test-im/internal/linkpreview"
 	"github.com/l-jessie/test-im/internal/logic"
 	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,30 +19,194 @@ func Router() *gin.Engine {
 	// DI
 	hub := types.NewHub()
 	go hub.Run()
-	chatService := logic.NewChatService(hub)
-	wsHandle := handle.NewWsHandle(hub, chatService)
+	messageStore := store.NewMessageStore()
+	receiptStore := store.NewReceiptStore()
+	conversationStore := store.NewConversationStore()
+	blobStore, err := store.NewLocalBlobStore(global.BlobDir)
+	if err != nil {
+		log.Fatalf("init blob store error: %v", err)
+	}
+
+	presenceService := logic.NewPresenceService(hub, store.NewPresenceStore())
+	profileStore := store.NewProfileStore()
+	directoryService := logic.NewDirectoryService(hub, store.NewUserStore(), profileStore, presenceService)
+	hub.AddConnectionListener(presenceService)
+	hub.AddConnectionListener(directoryService)
+
+	receiptService := logic.NewReceiptService(hub, messageStore, receiptStore)
+	conversationService := logic.NewConversationService(hub, messageStore, receiptStore, conversationStore, directoryService)
+	fileService := logic.NewFileService(hub, blobStore, store.NewFileStore())
+	pollService := logic.NewPollService(hub, store.NewPollStore())
+	linkPreviewFetcher := linkpreview.NewCachedFetcher(linkpreview.NewHTTPFetcher(linkpreview.Options{
+		Allow:    envList(global.LinkPreviewAllowEnv),
+		Deny:     envList(global.LinkPreviewDenyEnv),
+		MaxBytes: global.LinkPreviewMaxBytes,
+		Timeout:  global.LinkPreviewTimeout,
+	}), global.LinkPreviewCacheTTL)
+	linkPreviewService := logic.NewLinkPreviewService(hub, messageStore, linkPreviewFetcher)
+	privacyStore := store.NewPrivacyStore()
+	contactService := logic.NewContactService(hub, store.NewContactStore(), privacyStore, presenceService, directoryService)
+	presenceService.SetContactFunc(contactService.IsContact)
+	privacyService := logic.NewPrivacyService(privacyStore, contactService)
+	hub.SetDeliveryGuard(privacyService.CanSendDM)
+	hub.SetBlockGuard(privacyService.IsBlocked)
+	profileService := logic.NewProfileService(hub, profileStore, blobStore, directoryService, contactService)
+	chatService := logic.NewChatService(hub, messageStore, conversationStore, receiptService, fileService, pollService, linkPreviewService, privacyService, presenceService, logic.NewRoomService(hub))
+	uploadService, err := logic.NewUploadService(fileService, store.NewUploadStore(), global.UploadDir)
+	if err != nil {
+		log.Fatalf("init upload service error: %v", err)
+	}
+
+	searchService := logic.NewSearchService(hub, messageStore)
+	groupService := logic.NewGroupService(hub, directoryService, privacyService)
+	announcementService := logic.NewAnnouncementService(hub, store.NewAnnouncementStore())
+	deviceService := logic.NewDeviceService(hub, store.NewDeviceStore())
+	scheduleService := logic.NewScheduleService(hub, chatService, store.NewScheduleStore())
+	go logic.NewMessageSweeper(hub, messageStore, fileService, pollService).Run()
+	go scheduleService.Run()
+	go presenceService.Run()
+	go receiptService.Run()
+
+	wsHandle := handle.NewWsHandle(hub, chatService, receiptService, announcementService, deviceService)
 	roomHandle := handle.NewRoomHandle(hub, chatService)
-	usersHandle := handle.NewUsersHandle(hub)
+	usersHandle := handle.NewUsersHandle(presenceService, directoryService, profileService)
+	receiptHandle := handle.NewReceiptHandle(receiptService)
+	conversationHandle := handle.NewConversationHandle(conversationService)
+	fileHandle := handle.NewFileHandle(fileService)
+	uploadHandle := handle.NewUploadHandle(uploadService)
+	scheduleHandle := handle.NewScheduleHandle(scheduleService)
+	pollHandle := handle.NewPollHandle(pollService)
+	messageHandle := handle.NewMessageHandle(chatService)
+	searchHandle := handle.NewSearchHandle(searchService)
+	groupHandle := handle.NewGroupHandle(groupService)
+	announcementHandle := handle.NewAnnouncementHandle(announcementService)
+	contactHandle := handle.NewContactHandle(contactService, privacyService)
+	deviceHandle := handle.NewDeviceHandle(deviceService)
+	loginHandle := handle.NewLoginHandle(deviceService)
 
 	// 路由
 	router := gin.Default()
 	v1Group := router.Group("/v1/api")
 	v1Group.GET("/ping", handle.PingPongHandle)
-	v1Group.POST("/login", handle.LoginHandle)
+	v1Group.POST("/login", loginHandle.LoginHandleFunc)
 	v1Group.GET("/ws", wsHandle.WsHandleFunc)
 
-	roomGroup := v1Group.Group("/rooms")
+	adminGroup := v1Group.Group("/admin", handle.AdminAuth(os.Getenv(global.AdminTokenEnv)))
+	{
+		adminGroup.GET("/announcements", announcementHandle.GetAllAnnouncementsHandle)
+		adminGroup.POST("/announcements", announcementHandle.CreateAnnouncementHandle)
+	}
+
+	// 以下接口需要携带登录时签发的设备会话凭证，当前用户由凭证确定
+	sessionGroup := v1Group.Group("", handle.SessionAuth(deviceService))
+	sessionGroup.GET("/search", searchHandle.SearchHandle)
+	sessionGroup.GET("/announcements", announcementHandle.GetAnnouncementsHandle)
+
+	roomGroup := sessionGroup.Group("/rooms")
 	{
 		roomGroup.GET("", roomHandle.GetRoomsHandle)
 		roomGroup.POST("", roomHandle.CreateRoomHandle)
 		roomGroup.GET("/:roomId", roomHandle.GetRoomDetailHandle)
 		roomGroup.POST("/:roomId/join", roomHandle.JoinRoomHandle)
+		roomGroup.GET("/:roomId/receipts", receiptHandle.GetRoomReceiptsHandle)
 	}
 
-	usersGroup := v1Group.Group("users")
+	groupGroup := sessionGroup.Group("/groups")
+	{
+		groupGroup.GET("", groupHandle.GetGroupsHandle)
+		groupGroup.POST("", groupHandle.CreateGroupHandle)
+		groupGroup.GET("/:groupId", groupHandle.GetGroupDetailHandle)
+		groupGroup.POST("/:groupId/members", groupHandle.AddMembersHandle)
+		groupGroup.DELETE("/:groupId/members/:memberId", groupHandle.RemoveMemberHandle)
+	}
+
+	contactGroup := sessionGroup.Group("/contacts")
+	{
+		contactGroup.GET("", contactHandle.GetContactsHandle)
+		contactGroup.DELETE("/:contactId", contactHandle.RemoveContactHandle)
+		contactGroup.GET("/requests", contactHandle.GetFriendRequestsHandle)
+		contactGroup.POST("/requests", contactHandle.SendFriendRequestHandle)
+		contactGroup.POST("/requests/:requestId/accept", contactHandle.AcceptFriendRequestHandle)
+		contactGroup.POST("/requests/:requestId/decline", contactHandle.DeclineFriendRequestHandle)
+		contactGroup.DELETE("/requests/:requestId", contactHandle.CancelFriendRequestHandle)
+	}
+
+	meGroup := sessionGroup.Group("/me")
+	{
+		meGroup.GET("/devices", deviceHandle.GetDevicesHandle)
+		meGroup.POST("/devices", deviceHandle.LinkDeviceHandle)
+		meGroup.DELETE("/devices/:deviceId", deviceHandle.RevokeDeviceHandle)
+		meGroup.POST("/devices/:deviceId/restore", deviceHandle.RestoreDeviceHandle)
+		meGroup.GET("/profile", usersHandle.GetProfileHandle)
+		meGroup.PUT("/profile", usersHandle.UpdateProfileHandle)
+		meGroup.PUT("/avatar", usersHandle.UploadAvatarHandle)
+		meGroup.DELETE("/avatar", usersHandle.DeleteAvatarHandle)
+		meGroup.GET("/presence", usersHandle.GetPresenceHandle)
+		meGroup.PUT("/presence", usersHandle.UpdatePresenceHandle)
+		meGroup.GET("/privacy", contactHandle.GetPrivacyHandle)
+		meGroup.PUT("/privacy", contactHandle.UpdatePrivacyHandle)
+		meGroup.GET("/blocks", contactHandle.GetBlockedHandle)
+		meGroup.PUT("/blocks/:targetId", contactHandle.BlockHandle)
+		meGroup.DELETE("/blocks/:targetId", contactHandle.UnblockHandle)
+	}
+
+	usersGroup := sessionGroup.Group("users")
 	{
 		usersGroup.GET("", usersHandle.GetUsersHandle)
+		usersGroup.GET("/:userId", usersHandle.GetUserHandle)
+		usersGroup.GET("/:userId/avatar", usersHandle.GetAvatarHandle)
+	}
+
+	conversationGroup := sessionGroup.Group("/conversations")
+	{
+		conversationGroup.GET("", conversationHandle.GetConversationsHandle)
+		conversationGroup.GET("/:conversationId/settings", conversationHandle.GetSettingsHandle)
+		conversationGroup.PUT("/:conversationId/settings", conversationHandle.UpdateSettingsHandle)
+	}
+
+	fileGroup := sessionGroup.Group("/files")
+	{
+		fileGroup.POST("", fileHandle.UploadFileHandle)
+		fileGroup.GET("/:fileId", fileHandle.DownloadFileHandle)
+		fileGroup.GET("/:fileId/thumbnail", fileHandle.DownloadThumbnailHandle)
+	}
+
+	uploadGroup := sessionGroup.Group("/uploads")
+	{
+		uploadGroup.POST("", uploadHandle.CreateUploadHandle)
+		uploadGroup.GET("/:uploadId", uploadHandle.GetUploadHandle)
+		uploadGroup.PATCH("/:uploadId", uploadHandle.UploadChunkHandle)
+		uploadGroup.DELETE("/:uploadId", uploadHandle.CancelUploadHandle)
+	}
+
+	scheduleGroup := sessionGroup.Group("/scheduled")
+	{
+		scheduleGroup.GET("", scheduleHandle.GetSchedulesHandle)
+		scheduleGroup.POST("", scheduleHandle.CreateScheduleHandle)
+		scheduleGroup.PUT("/:scheduleId", scheduleHandle.UpdateScheduleHandle)
+		scheduleGroup.DELETE("/:scheduleId", scheduleHandle.CancelScheduleHandle)
+	}
+
+	pollGroup := sessionGroup.Group("/polls")
+	{
+		pollGroup.GET("/:pollId", pollHandle.GetPollResultHandle)
+	}
+
+	messageGroup := sessionGroup.Group("/messages")
+	{
+		messageGroup.POST("/:messageId/forward", messageHandle.ForwardMessageHandle)
 	}
 
 	return router
 }
+
+// envList 读取逗号分隔的环境变量，忽略空白项。
+func envList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
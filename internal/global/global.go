@@ -9,4 +9,43 @@ const (
 	PongWait       = 60 * time.Second
 	PingPeriod     = (PongWait * 9) / 10
 	MaxMessageSize = 1024 * 8
+
+	ReadBufferSize  = 4 << 10  // websocket 读缓冲区大小
+	WriteBufferSize = 16 << 10 // websocket 写缓冲区大小，写缓冲区在连接之间复用
+
+	MaxUploadSize = 32 << 20 // 单个上传文件的最大字节数
+	BlobDir       = "data/blobs"
+
+	MaxResumableUploadSize = 4 << 30        // 分块上传文件的最大字节数
+	MaxUploadChunkSize     = 8 << 20        // 单个分块的最大字节数
+	UploadExpire           = 24 * time.Hour // 分块上传在无进展多久后失效
+	UploadDir              = "data/uploads"
+
+	DeliveredQueueSize = 1024 // 等待发送的送达回执数，队列满时丢弃新的回执
+
+	MaxMessageTTL     = 7 * 24 * time.Hour // 阅后即焚消息的最长存活时间
+	ExpirySweepPeriod = time.Second        // 清理过期消息的间隔
+
+	ScheduleCheckPeriod = time.Second          // 检查到期定时消息的间隔
+	MaxScheduleAhead    = 365 * 24 * time.Hour // 定时消息最远可以设置到多久以后
+
+	LinkPreviewTimeout  = 5 * time.Second         // 抓取一个链接的超时时间
+	LinkPreviewMaxBytes = 512 << 10               // 最多读取的页面字节数
+	LinkPreviewCacheTTL = time.Hour               // 预览结果的缓存时间
+	MaxLinkPreviews     = 3                       // 每条消息最多生成的预览数
+	LinkPreviewAllowEnv = "IM_LINK_PREVIEW_ALLOW" // 链接预览域名白名单的环境变量，多个域名用逗号分隔，未设置时不限制
+	LinkPreviewDenyEnv  = "IM_LINK_PREVIEW_DENY"  // 链接预览域名黑名单的环境变量，优先于白名单
+
+	AdminTokenEnv           = "IM_ADMIN_TOKEN" // 管理员接口令牌的环境变量，未设置时管理员接口不可用
+	MaxPendingAnnouncements = 50               // 上线时最多补发的公告数
+
+	PresenceIdleAway    = 5 * time.Minute  // 所有设备空闲多久后自动显示为离开
+	PresenceCheckPeriod = 10 * time.Second // 检查空闲和自定义状态过期的间隔
+
+	PresenceCoalesceWindow   = 500 * time.Millisecond // 合并状态变化通知的窗口
+	MaxPresenceSubscriptions = 500                    // 每个连接最多订阅的用户数
+
+	MaxAvatarSize        = 5 << 20 // 头像原图的最大字节数，保存时缩放为缩略图大小
+	MaxDisplayNameLength = 32      // 显示名称的最大字符数
+	MaxProfileBioLength  = 280     // 个人简介的最大字符数
 )
@@ -0,0 +1,62 @@
+package handle
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnnouncementHandle struct {
+	announcementService *logic.AnnouncementService
+}
+
+func NewAnnouncementHandle(announcementService *logic.AnnouncementService) *AnnouncementHandle {
+	return &AnnouncementHandle{announcementService: announcementService}
+}
+
+// AdminAuth 校验请求头 X-Admin-Token，token 为空时拒绝所有请求。
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader("X-Admin-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": 0, "msg": "需要管理员权限"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CreateAnnouncementHandle 发布公告，仅管理员可用。
+func (h *AnnouncementHandle) CreateAnnouncementHandle(c *gin.Context) {
+	var req dto.CreateAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	announcement, err := h.announcementService.Create(&req)
+	if errors.Is(err, logic.InvalidTargetError) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "只能选择一种发送目标"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "公告内容无效"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": announcement})
+}
+
+// GetAllAnnouncementsHandle 返回所有公告及其目标，仅管理员可用。
+func (h *AnnouncementHandle) GetAllAnnouncementsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.announcementService.List()})
+}
+
+// GetAnnouncementsHandle 返回发给用户的公告。
+func (h *AnnouncementHandle) GetAnnouncementsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.announcementService.ListByUser(sessionUserID(c))})
+}
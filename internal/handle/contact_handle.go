@@ -0,0 +1,148 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactHandle struct {
+	contactService *logic.ContactService
+	privacyService *logic.PrivacyService
+}
+
+func NewContactHandle(contactService *logic.ContactService, privacyService *logic.PrivacyService) *ContactHandle {
+	return &ContactHandle{contactService: contactService, privacyService: privacyService}
+}
+
+// GetContactsHandle 返回联系人列表和在线状态。
+func (h *ContactHandle) GetContactsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.contactService.Contacts(sessionUserID(c))})
+}
+
+// RemoveContactHandle 解除联系人关系。
+func (h *ContactHandle) RemoveContactHandle(c *gin.Context) {
+	if err := h.contactService.RemoveContact(sessionUserID(c), c.Param("contactId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "不是联系人"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+// GetFriendRequestsHandle 返回收到和发出的待处理好友请求。
+func (h *ContactHandle) GetFriendRequestsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.contactService.PendingRequests(sessionUserID(c))})
+}
+
+func (h *ContactHandle) SendFriendRequestHandle(c *gin.Context) {
+	var req dto.SendFriendRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	request, err := h.contactService.SendRequest(sessionUserID(c), req.To, req.Message)
+	if err != nil {
+		friendRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": request})
+}
+
+func (h *ContactHandle) AcceptFriendRequestHandle(c *gin.Context) {
+	h.handleFriendRequest(c, h.contactService.Accept)
+}
+
+func (h *ContactHandle) DeclineFriendRequestHandle(c *gin.Context) {
+	h.handleFriendRequest(c, h.contactService.Decline)
+}
+
+// CancelFriendRequestHandle 撤回自己发出的好友请求。
+func (h *ContactHandle) CancelFriendRequestHandle(c *gin.Context) {
+	request, err := h.contactService.Cancel(sessionUserID(c), c.Param("requestId"))
+	if err != nil {
+		friendRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": request})
+}
+
+func (h *ContactHandle) handleFriendRequest(c *gin.Context, handleFunc func(userID, requestID string) (*store.FriendRequest, error)) {
+	request, err := handleFunc(sessionUserID(c), c.Param("requestId"))
+	if err != nil {
+		friendRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": request})
+}
+
+// GetPrivacyHandle 返回隐私设置。
+func (h *ContactHandle) GetPrivacyHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.privacyService.Settings(sessionUserID(c))})
+}
+
+// UpdatePrivacyHandle 修改谁可以给自己发私聊。
+func (h *ContactHandle) UpdatePrivacyHandle(c *gin.Context) {
+	var req dto.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	settings, err := h.privacyService.SetDMPolicy(sessionUserID(c), req.DMPolicy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "隐私设置错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": settings})
+}
+
+// GetBlockedHandle 返回屏蔽列表。
+func (h *ContactHandle) GetBlockedHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.privacyService.Blocked(sessionUserID(c))})
+}
+
+// BlockHandle 屏蔽用户。
+func (h *ContactHandle) BlockHandle(c *gin.Context) {
+	if err := h.privacyService.Block(sessionUserID(c), c.Param("targetId")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "不能屏蔽该用户"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+// UnblockHandle 取消屏蔽。
+func (h *ContactHandle) UnblockHandle(c *gin.Context) {
+	if !h.privacyService.Unblock(sessionUserID(c), c.Param("targetId")) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "没有屏蔽该用户"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+func friendRequestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.FriendRequestNotFindError):
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "好友请求不存在"})
+	case errors.Is(err, logic.FriendRequestHandledError):
+		c.JSON(http.StatusConflict, gin.H{"code": 0, "msg": "好友请求已处理"})
+	case errors.Is(err, logic.AlreadyContactError):
+		c.JSON(http.StatusConflict, gin.H{"code": 0, "msg": "已经是联系人"})
+	case errors.Is(err, logic.BlockedError):
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "你已被对方屏蔽"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "好友请求参数错误"})
+	}
+}
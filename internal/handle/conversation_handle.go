@@ -0,0 +1,59 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationHandle struct {
+	conversationService *logic.ConversationService
+}
+
+func NewConversationHandle(conversationService *logic.ConversationService) *ConversationHandle {
+	return &ConversationHandle{conversationService: conversationService}
+}
+
+// GetConversationsHandle 返回当前用户的会话列表，包含最后一条消息预览和未读数。
+func (h *ConversationHandle) GetConversationsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 1,
+		"msg":  "success",
+		"data": h.conversationService.ListConversations(sessionUserID(c)),
+	})
+}
+
+// GetSettingsHandle 返回会话设置。
+func (h *ConversationHandle) GetSettingsHandle(c *gin.Context) {
+	settings, err := h.conversationService.Settings(sessionUserID(c), c.Param("conversationId"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "无权访问该会话"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": settings})
+}
+
+// UpdateSettingsHandle 修改会话的阅后即焚默认时间。
+func (h *ConversationHandle) UpdateSettingsHandle(c *gin.Context) {
+	var req dto.UpdateConversationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	settings, err := h.conversationService.SetDisappearing(sessionUserID(c), c.Param("conversationId"), req.DisappearingSeconds)
+	if errors.Is(err, logic.InvalidDisappearingError) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "阅后即焚时间错误"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "无权访问该会话"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": settings})
+}
@@ -0,0 +1,112 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionTokenHeader 是请求携带当前设备会话凭证的请求头。
+const sessionTokenHeader = "X-Session-Token"
+
+// 会话校验通过后保存在 gin.Context 中的当前用户和设备。
+const (
+	sessionUserKey   = "sessionUserId"
+	sessionDeviceKey = "sessionDeviceId"
+)
+
+// SessionAuth 校验请求携带的设备会话凭证，并把凭证所属的用户和设备保存到 gin.Context 中，
+// 处理函数通过 sessionUserID 获取当前用户，不再信任请求参数中的 userId。
+// 图片、文件等由浏览器直接加载的地址无法设置请求头，可以改用 session 查询参数携带凭证。
+func SessionAuth(deviceService *logic.DeviceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(sessionTokenHeader)
+		if token == "" {
+			token = c.Query("session")
+		}
+
+		userID, deviceID, err := deviceService.Authorize(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 0, "msg": "会话无效"})
+			return
+		}
+
+		c.Set(sessionUserKey, userID)
+		c.Set(sessionDeviceKey, deviceID)
+		c.Next()
+	}
+}
+
+// sessionUserID 返回 SessionAuth 校验通过的当前用户ID。
+func sessionUserID(c *gin.Context) string {
+	return c.GetString(sessionUserKey)
+}
+
+// sessionDeviceID 返回 SessionAuth 校验通过的当前设备ID。
+func sessionDeviceID(c *gin.Context) string {
+	return c.GetString(sessionDeviceKey)
+}
+
+type DeviceHandle struct {
+	deviceService *logic.DeviceService
+}
+
+func NewDeviceHandle(deviceService *logic.DeviceService) *DeviceHandle {
+	return &DeviceHandle{deviceService: deviceService}
+}
+
+// GetDevicesHandle 返回当前用户的在线设备。
+func (h *DeviceHandle) GetDevicesHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.deviceService.List(sessionUserID(c))})
+}
+
+// LinkDeviceHandle 为用户的另一个设备签发会话凭证。
+func (h *DeviceHandle) LinkDeviceHandle(c *gin.Context) {
+	var req dto.LinkDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+
+	session, err := h.deviceService.Link(sessionUserID(c), req.DeviceID)
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": gin.H{"deviceId": req.DeviceID, "session": session}})
+}
+
+// RevokeDeviceHandle 强制断开设备并使其会话失效。
+func (h *DeviceHandle) RevokeDeviceHandle(c *gin.Context) {
+	closed, err := h.deviceService.Revoke(sessionUserID(c), c.Param("deviceId"))
+	if err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": gin.H{"closed": closed}})
+}
+
+// RestoreDeviceHandle 恢复被移除的设备。
+func (h *DeviceHandle) RestoreDeviceHandle(c *gin.Context) {
+	if err := h.deviceService.Restore(sessionUserID(c), c.Param("deviceId")); err != nil {
+		deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+func deviceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.DeviceNotFindError):
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "设备不存在"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+	}
+}
@@ -0,0 +1,130 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/entity"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileHandle struct {
+	fileService *logic.FileService
+}
+
+func NewFileHandle(fileService *logic.FileService) *FileHandle {
+	return &FileHandle{fileService: fileService}
+}
+
+// UploadFileHandle 接收 multipart 上传，表单字段: file, type(2 房间 / 3 私聊 / 11 群组), to。
+func (h *FileHandle) UploadFileHandle(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, global.MaxUploadSize+1<<20)
+
+	userID := sessionUserID(c)
+	to := c.PostForm("to")
+	messageType, err := strconv.Atoi(c.PostForm("type"))
+	if to == "" || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "文件不能为空"})
+		return
+	}
+
+	record, err := h.fileService.Upload(userID, types.MessageType(messageType), to, header)
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"code": 0, "msg": fileErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": fileResponse(record)})
+}
+
+// DownloadFileHandle 校验请求者属于文件所在的会话后返回文件内容。
+func (h *FileHandle) DownloadFileHandle(c *gin.Context) {
+	userID := sessionUserID(c)
+	record, reader, err := h.fileService.Open(userID, c.Param("fileId"))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"code": 0, "msg": fileErrorMsg(err)})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, record.Size, record.MIME, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": record.Name}),
+	})
+}
+
+// DownloadThumbnailHandle 返回图片文件的缩略图。
+func (h *FileHandle) DownloadThumbnailHandle(c *gin.Context) {
+	userID := sessionUserID(c)
+	thumbnail, reader, err := h.fileService.OpenThumbnail(userID, c.Param("fileId"))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"code": 0, "msg": fileErrorMsg(err)})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, thumbnail.Size, thumbnail.MIME, reader, nil)
+}
+
+func fileResponse(record *store.FileRecord) *dto.FileResponse {
+	resp := &dto.FileResponse{
+		ID:         record.ID,
+		Name:       record.Name,
+		Size:       record.Size,
+		MIME:       record.MIME,
+		URL:        fmt.Sprintf("/v1/api/files/%s", record.ID),
+		CreateTime: entity.BizTimeFull(record.CreateTime),
+		Width:      record.Width,
+		Height:     record.Height,
+	}
+	if record.Thumbnail != nil {
+		resp.ThumbnailURL = fmt.Sprintf("/v1/api/files/%s/thumbnail", record.ID)
+	}
+	return resp
+}
+
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, logic.FileNotFindError), errors.Is(err, store.BlobNotFindError):
+		return http.StatusNotFound
+	case errors.Is(err, logic.NotParticipantError):
+		return http.StatusForbidden
+	case errors.Is(err, logic.FileTooLargeError):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, logic.InvalidFileTypeError), errors.Is(err, logic.InvalidImageError):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func fileErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, logic.FileNotFindError), errors.Is(err, store.BlobNotFindError):
+		return "文件不存在"
+	case errors.Is(err, logic.NotParticipantError):
+		return "无权访问该会话"
+	case errors.Is(err, logic.FileTooLargeError):
+		return "文件过大"
+	case errors.Is(err, logic.InvalidFileTypeError):
+		return "参数错误"
+	case errors.Is(err, logic.InvalidImageError):
+		return "图片格式错误"
+	}
+	log.Printf("file error: %v", err)
+	return "服务器错误"
+}
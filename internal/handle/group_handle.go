@@ -0,0 +1,95 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandle struct {
+	groupService *logic.GroupService
+}
+
+func NewGroupHandle(groupService *logic.GroupService) *GroupHandle {
+	return &GroupHandle{groupService: groupService}
+}
+
+// GetGroupsHandle 返回用户所在的群组。
+func (h *GroupHandle) GetGroupsHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.groupService.List(sessionUserID(c))})
+}
+
+func (h *GroupHandle) CreateGroupHandle(c *gin.Context) {
+	var req dto.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	group, err := h.groupService.Create(sessionUserID(c), req.Name, req.Members)
+	if err != nil {
+		groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": group})
+}
+
+func (h *GroupHandle) GetGroupDetailHandle(c *gin.Context) {
+	group, err := h.groupService.Detail(sessionUserID(c), c.Param("groupId"))
+	if err != nil {
+		groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": group})
+}
+
+func (h *GroupHandle) AddMembersHandle(c *gin.Context) {
+	var req dto.AddGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	group, err := h.groupService.AddMembers(sessionUserID(c), c.Param("groupId"), req.Members)
+	if err != nil {
+		groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": group})
+}
+
+// RemoveMemberHandle 移除成员，memberId 为自己时表示退出群组。
+func (h *GroupHandle) RemoveMemberHandle(c *gin.Context) {
+	if err := h.groupService.RemoveMember(sessionUserID(c), c.Param("groupId"), c.Param("memberId")); err != nil {
+		groupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+func groupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.GroupNotFindError):
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "群组不存在"})
+	case errors.Is(err, logic.NotGroupMemberError):
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "不是群组成员"})
+	case errors.Is(err, logic.NotGroupOwnerError):
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "只有群主可以移除其他成员"})
+	case errors.Is(err, logic.GroupTooLargeError):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "群组成员过多"})
+	case errors.Is(err, logic.BlockedError):
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "你已被对方屏蔽"})
+	case errors.Is(err, logic.DMNotAllowedError):
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "对方不接收你的消息"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "群组参数错误"})
+	}
+}
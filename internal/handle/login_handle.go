@@ -3,12 +3,22 @@ package handle
 import (
 	"net/http"
 
+	"github.com/l-jessie/test-im/internal/logic"
 	"github.com/l-jessie/test-im/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func LoginHandle(c *gin.Context) {
+type LoginHandle struct {
+	deviceService *logic.DeviceService
+}
+
+func NewLoginHandle(deviceService *logic.DeviceService) *LoginHandle {
+	return &LoginHandle{deviceService: deviceService}
+}
+
+// LoginHandleFunc 登录并为当前设备签发会话凭证，建立 websocket 连接时需要携带。
+func (h *LoginHandle) LoginHandleFunc(c *gin.Context) {
 	var body map[string]string
 	err := c.ShouldBindBodyWithJSON(&body)
 	if err != nil {
@@ -30,12 +40,24 @@ func LoginHandle(c *gin.Context) {
 		return
 	}
 
+	userID := utils.GenerateUUID()
+	session, err := h.deviceService.Issue(userID, body["deviceId"])
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "设备ID不能为空",
+			"data": nil,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 1,
 		"msg":  "登录成功",
 		"data": gin.H{
-			"id":       utils.GenerateUUID(),
+			"id":       userID,
 			"username": username,
+			"session":  session,
 		},
 	})
 }
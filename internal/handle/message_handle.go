@@ -0,0 +1,67 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/markdown"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageHandle struct {
+	chatService *logic.ChatService
+}
+
+func NewMessageHandle(chatService *logic.ChatService) *MessageHandle {
+	return &MessageHandle{chatService: chatService}
+}
+
+// ForwardMessageHandle 把消息转发到另一个房间或私聊。
+func (h *MessageHandle) ForwardMessageHandle(c *gin.Context) {
+	var req dto.ForwardMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	message, err := h.chatService.Forward(sessionUserID(c), c.Param("messageId"), req.Type, req.To)
+	if err != nil {
+		c.JSON(messageErrorStatus(err), gin.H{"code": 0, "msg": messageErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": message})
+}
+
+func messageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, logic.MessageNotFindError), errors.Is(err, logic.FileNotFindError):
+		return http.StatusNotFound
+	case errors.Is(err, logic.NotParticipantError), errors.Is(err, logic.ForwardNotAllowedError), errors.Is(err, logic.DMNotAllowedError), errors.Is(err, logic.BlockedError):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func messageErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, logic.MessageNotFindError):
+		return "消息不存在"
+	case errors.Is(err, logic.FileNotFindError):
+		return "文件不存在"
+	case errors.Is(err, logic.NotParticipantError):
+		return "无权访问该会话"
+	case errors.Is(err, logic.ForwardNotAllowedError):
+		return "该消息不允许转发"
+	case errors.Is(err, logic.DMNotAllowedError):
+		return "对方不接收你的私聊"
+	case errors.Is(err, logic.BlockedError):
+		return "你已被对方屏蔽"
+	case errors.Is(err, markdown.UnsafeMarkdownError):
+		return "消息包含不安全的内容"
+	}
+	return "参数错误"
+}
@@ -0,0 +1,32 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PollHandle struct {
+	pollService *logic.PollService
+}
+
+func NewPollHandle(pollService *logic.PollService) *PollHandle {
+	return &PollHandle{pollService: pollService}
+}
+
+// GetPollResultHandle 返回投票的当前票数和查询者自己的选择。
+func (h *PollHandle) GetPollResultHandle(c *gin.Context) {
+	result, err := h.pollService.Result(sessionUserID(c), c.Param("pollId"))
+	if errors.Is(err, logic.PollNotFindError) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "投票不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "无权访问该会话"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": result})
+}
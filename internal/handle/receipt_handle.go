@@ -0,0 +1,32 @@
+package handle
+
+import (
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReceiptHandle struct {
+	receiptService *logic.ReceiptService
+}
+
+func NewReceiptHandle(receiptService *logic.ReceiptService) *ReceiptHandle {
+	return &ReceiptHandle{receiptService: receiptService}
+}
+
+// GetRoomReceiptsHandle 返回房间内每个用户的已读水位。
+func (h *ReceiptHandle) GetRoomReceiptsHandle(c *gin.Context) {
+	watermarks, err := h.receiptService.RoomWatermarks(sessionUserID(c), c.Param("roomId"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": 0, "msg": "无权访问该会话"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 1,
+		"msg":  "success",
+		"data": watermarks,
+	})
+}
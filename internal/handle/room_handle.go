@@ -21,22 +21,24 @@ func NewRoomHandle(hub *types.Hub, chatService *logic.ChatService) *RoomHandle {
 	return &RoomHandle{hub: hub, chatService: chatService}
 }
 
+// GetRoomsHandle 返回所有房间，version 是房间列表的版本，用于对齐房间增量事件。
 func (h *RoomHandle) GetRoomsHandle(c *gin.Context) {
-	rooms := make([]*dto.RoomsResponse, 0, len(h.hub.Rooms))
-	for _, room := range h.hub.Rooms {
+	summaries, version := h.hub.RoomList()
+	rooms := make([]*dto.RoomsResponse, 0, len(summaries))
+	for _, room := range summaries {
 		rooms = append(rooms, &dto.RoomsResponse{
 			ID:          room.ID,
 			Name:        room.Name,
-			HasPassword: room.Password != "",
+			HasPassword: room.HasPassword,
 			UserID:      room.UserID,
 			UserName:    room.UserName,
-			Count:       len(room.Clients),
-			CreateTime:  entity.BizTimeFull(room.CreateTime),
+			Count:       room.Count,
+			CreateTime:  room.CreateTime,
 		})
 	}
 
 	c.JSON(http.StatusOK,
-		gin.H{"code": 1, "msg": "success", "data": rooms},
+		gin.H{"code": 1, "msg": "success", "data": rooms, "version": version},
 	)
 }
 
@@ -47,22 +49,14 @@ func (h *RoomHandle) CreateRoomHandle(c *gin.Context) {
 		return
 	}
 
-	var oneClient types.Client
-	userCli := h.hub.Users[req.UserID]
-	if len(userCli) >= 1 {
-		for cli := range userCli {
-			oneClient = *cli
-			break
-		}
-	} else {
-		oneClient = types.Client{}
-	}
+	userID := sessionUserID(c)
+	userName, _ := h.hub.UserName(userID)
 
 	roomID := utils.GenerateUUID()
-	room := types.NewRoom(roomID, req.Name, req.Password, req.UserID, oneClient.UserName)
+	room := types.NewRoom(roomID, req.Name, req.Password, userID, userName)
 
 	h.hub.CreateRoom <- &types.CreateRoomEvent{
-		UserID: req.UserID,
+		UserID: userID,
 		RoomID: roomID,
 		Room:   room,
 	}
@@ -96,40 +90,39 @@ func (h *RoomHandle) JoinRoomHandle(c *gin.Context) {
 	}
 
 	h.hub.JoinRoom <- &types.JoinRoomEvent{
-		UserID:   req.UserID,
-		DeviceID: req.DeviceID,
+		UserID:   sessionUserID(c),
+		DeviceID: sessionDeviceID(c),
 		RoomID:   req.RoomID,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
 }
 
+// GetRoomDetailHandle 返回房间详情，version 是房间成员的版本，用于对齐成员增量事件。
 func (h *RoomHandle) GetRoomDetailHandle(c *gin.Context) {
 	roomID := c.Param("roomId")
 
-	room, ok := h.hub.Rooms[roomID]
+	room, members, version, ok := h.hub.RoomDetail(roomID)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "房间不存在"})
 		return
 	}
 
-	users := make([]*dto.UserVO, 0, len(room.Clients))
-	i := 0
-	for client := range room.Clients {
-		i++
+	users := make([]*dto.UserVO, 0, len(members))
+	for _, member := range members {
 		users = append(users, &dto.UserVO{
-			ID:   client.UserId,
-			Name: client.UserName,
+			ID:   member.UserID,
+			Name: member.UserName,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": &dto.RoomsDetailResponse{
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "version": version, "data": &dto.RoomsDetailResponse{
 		ID:         room.ID,
 		Name:       room.Name,
 		UserID:     room.UserID,
 		UserName:   "room-admin",
-		Count:      len(room.Clients),
-		CreateTime: entity.BizTimeFull(room.CreateTime),
+		Count:      room.Count,
+		CreateTime: room.CreateTime,
 		Users:      users,
 	}})
 }
@@ -0,0 +1,94 @@
+package handle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/markdown"
+	"github.com/l-jessie/test-im/internal/model/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandle struct {
+	scheduleService *logic.ScheduleService
+}
+
+func NewScheduleHandle(scheduleService *logic.ScheduleService) *ScheduleHandle {
+	return &ScheduleHandle{scheduleService: scheduleService}
+}
+
+func (h *ScheduleHandle) CreateScheduleHandle(c *gin.Context) {
+	var req dto.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleService.Create(sessionUserID(c), req.Type, req.To, req.Payload, req.TTL, req.SendAt)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"code": 0, "msg": scheduleErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": schedule})
+}
+
+func (h *ScheduleHandle) GetSchedulesHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.scheduleService.List(sessionUserID(c))})
+}
+
+func (h *ScheduleHandle) UpdateScheduleHandle(c *gin.Context) {
+	var req dto.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleService.Update(sessionUserID(c), c.Param("scheduleId"), req.Payload, req.SendAt)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"code": 0, "msg": scheduleErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": schedule})
+}
+
+func (h *ScheduleHandle) CancelScheduleHandle(c *gin.Context) {
+	schedule, err := h.scheduleService.Cancel(sessionUserID(c), c.Param("scheduleId"))
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), gin.H{"code": 0, "msg": scheduleErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": schedule})
+}
+
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, logic.ScheduleNotFindError):
+		return http.StatusNotFound
+	case errors.Is(err, logic.NotParticipantError):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func scheduleErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, logic.ScheduleNotFindError):
+		return "定时消息不存在或已发送"
+	case errors.Is(err, logic.NotParticipantError):
+		return "无权访问该会话"
+	case errors.Is(err, logic.InvalidSendTimeError):
+		return "发送时间错误"
+	case errors.Is(err, logic.FileNotFindError):
+		return "文件不存在"
+	case errors.Is(err, logic.InvalidPollError):
+		return "投票内容错误"
+	case errors.Is(err, logic.InvalidMarkdownError), errors.Is(err, markdown.UnsafeMarkdownError):
+		return "消息内容错误"
+	}
+	return "参数错误"
+}
@@ -0,0 +1,61 @@
+package handle
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandle struct {
+	searchService *logic.SearchService
+}
+
+func NewSearchHandle(searchService *logic.SearchService) *SearchHandle {
+	return &SearchHandle{searchService: searchService}
+}
+
+// SearchHandle 搜索消息，参数: q, 可选 from, conversationId, start, end (unix 秒), limit。
+func (h *SearchHandle) SearchHandle(c *gin.Context) {
+	userID := sessionUserID(c)
+	query := &store.SearchQuery{
+		Text:           c.Query("q"),
+		From:           c.Query("from"),
+		ConversationID: c.Query("conversationId"),
+	}
+	var err error
+	if query.Start, err = queryInt(c, "start"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+	if query.End, err = queryInt(c, "end"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+	query.Limit = int(limit)
+
+	messages, err := h.searchService.Search(userID, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "搜索内容不能为空"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": messages})
+}
+
+// queryInt 解析可选的整数查询参数，未提供时返回 0。
+func queryInt(c *gin.Context, key string) (int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,162 @@
+package handle
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/logic"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 分块上传使用的请求头，格式参考 tus 协议
+const (
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadChecksumHeader = "Upload-Checksum" // "sha256 <base64>"
+)
+
+type UploadHandle struct {
+	uploadService *logic.UploadService
+}
+
+func NewUploadHandle(uploadService *logic.UploadService) *UploadHandle {
+	return &UploadHandle{uploadService: uploadService}
+}
+
+// CreateUploadHandle 创建一个可续传的上传。
+func (h *UploadHandle) CreateUploadHandle(c *gin.Context) {
+	var req dto.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+	if req.To == "" || req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+
+	session, err := h.uploadService.Create(sessionUserID(c), req.Type, req.To, req.Name, req.Size, strings.ToLower(req.Checksum))
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"code": 0, "msg": uploadErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": uploadResponse(session, session.Offset, nil)})
+}
+
+// GetUploadHandle 返回已接收的偏移量，断线后客户端据此继续上传。
+func (h *UploadHandle) GetUploadHandle(c *gin.Context) {
+	session, err := h.uploadService.Get(sessionUserID(c), c.Param("uploadId"))
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"code": 0, "msg": uploadErrorMsg(err)})
+		return
+	}
+
+	session.Lock()
+	offset := session.Offset
+	session.Unlock()
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": uploadResponse(session, offset, nil)})
+}
+
+// UploadChunkHandle 写入一个分块，请求体为分块数据。
+func (h *UploadHandle) UploadChunkHandle(c *gin.Context) {
+	userID := sessionUserID(c)
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+	checksum, ok := parseChecksumHeader(c.GetHeader(uploadChecksumHeader))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "分块校验值错误"})
+		return
+	}
+
+	session, err := h.uploadService.Get(userID, c.Param("uploadId"))
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"code": 0, "msg": uploadErrorMsg(err)})
+		return
+	}
+
+	newOffset, record, err := h.uploadService.WriteChunk(userID, session.ID, offset, checksum, c.Request.Body)
+	c.Header(uploadOffsetHeader, strconv.FormatInt(newOffset, 10))
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"code": 0, "msg": uploadErrorMsg(err), "data": uploadResponse(session, newOffset, nil)})
+		return
+	}
+
+	var file *dto.FileResponse
+	if record != nil {
+		file = fileResponse(record)
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": uploadResponse(session, newOffset, file)})
+}
+
+// CancelUploadHandle 取消上传并删除已接收的数据。
+func (h *UploadHandle) CancelUploadHandle(c *gin.Context) {
+	if err := h.uploadService.Cancel(sessionUserID(c), c.Param("uploadId")); err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"code": 0, "msg": uploadErrorMsg(err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success"})
+}
+
+// parseChecksumHeader 解析 "sha256 <base64>" 格式的校验值。
+func parseChecksumHeader(header string) ([]byte, bool) {
+	algorithm, value, ok := strings.Cut(header, " ")
+	if !ok || algorithm != "sha256" {
+		return nil, false
+	}
+	checksum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	return checksum, true
+}
+
+func uploadResponse(session *store.UploadSession, offset int64, file *dto.FileResponse) *dto.UploadResponse {
+	return &dto.UploadResponse{
+		ID:        session.ID,
+		Name:      session.Name,
+		Size:      session.Size,
+		Offset:    offset,
+		ChunkSize: global.MaxUploadChunkSize,
+		File:      file,
+	}
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, logic.UploadNotFindError):
+		return http.StatusNotFound
+	case errors.Is(err, logic.UploadOffsetError):
+		return http.StatusConflict
+	case errors.Is(err, logic.ChecksumMismatchError):
+		return http.StatusBadRequest
+	case errors.Is(err, logic.UploadChunkTooLargeError):
+		return http.StatusRequestEntityTooLarge
+	}
+	return fileErrorStatus(err)
+}
+
+func uploadErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, logic.UploadNotFindError):
+		return "上传不存在"
+	case errors.Is(err, logic.UploadOffsetError):
+		return "偏移量不匹配"
+	case errors.Is(err, logic.ChecksumMismatchError):
+		return "校验值不匹配"
+	case errors.Is(err, logic.UploadChunkTooLargeError):
+		return "分块过大"
+	}
+	return fileErrorMsg(err)
+}
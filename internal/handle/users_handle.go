@@ -1,37 +1,187 @@
 package handle
 
 import (
+	"errors"
+	"net/http"
+
+	"github.com/l-jessie/test-im/internal/global"
+
+	"github.com/l-jessie/test-im/internal/logic"
 	"github.com/l-jessie/test-im/internal/model/dto"
-	"github.com/l-jessie/test-im/internal/model/types"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UsersHandle struct {
-	hub *types.Hub
+	presenceService  *logic.PresenceService
+	directoryService *logic.DirectoryService
+	profileService   *logic.ProfileService
 }
 
-func NewUsersHandle(hub *types.Hub) *UsersHandle {
+func NewUsersHandle(presenceService *logic.PresenceService, directoryService *logic.DirectoryService, profileService *logic.ProfileService) *UsersHandle {
 	return &UsersHandle{
-		hub: hub,
+		presenceService:  presenceService,
+		directoryService: directoryService,
+		profileService:   profileService,
+	}
+}
+
+// GetUsersHandle 默认返回在线用户，隐身的用户不出现在列表中。
+// all=1 时分页返回目录中的所有用户，包括离线用户，在线的排在前面，参数: offset, limit。
+func (h *UsersHandle) GetUsersHandle(c *gin.Context) {
+	if c.Query("all") != "1" {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": h.directoryService.Online()})
+		return
+	}
+
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "参数错误"})
+		return
+	}
+
+	users, total := h.directoryService.List(int(offset), int(limit))
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": users, "total": total})
+}
+
+// GetUserHandle 返回单个用户的信息和最后在线时间。
+func (h *UsersHandle) GetUserHandle(c *gin.Context) {
+	user, err := h.directoryService.Get(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "用户不存在"})
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": user})
+}
+
+// GetPresenceHandle 返回自己的状态设置和其他用户看到的状态。
+func (h *UsersHandle) GetPresenceHandle(c *gin.Context) {
+	userID := sessionUserID(c)
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": gin.H{
+		"settings": h.presenceService.Settings(userID),
+		"presence": h.presenceService.Presence(userID),
+	}})
 }
 
-func (h *UsersHandle) GetUsersHandle(context *gin.Context) {
-	users := h.hub.Users
+// UpdatePresenceHandle 修改自己的状态和自定义状态。
+func (h *UsersHandle) UpdatePresenceHandle(c *gin.Context) {
+	var req dto.UpdatePresenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	settings, err := h.presenceService.SetPresence(sessionUserID(c), req.State, req.StatusText, req.StatusExpireAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "状态参数错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": settings})
+}
 
-	userInfos := make([]*dto.UserVO, 0, len(users))
-	clients := h.hub.Clients
-	for cli := range clients {
-		userInfos = append(userInfos, &dto.UserVO{
-			ID:   cli.UserId,
-			Name: cli.UserName,
-		})
+// GetAvatarHandle 返回用户头像。
+func (h *UsersHandle) GetAvatarHandle(c *gin.Context) {
+	avatar, reader, err := h.profileService.OpenAvatar(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 0, "msg": "头像不存在"})
+		return
 	}
+	defer reader.Close()
 
-	context.JSON(200, gin.H{
-		"code": 1,
-		"msg":  "success",
-		"data": userInfos,
+	c.DataFromReader(http.StatusOK, avatar.Size, avatar.MIME, reader, map[string]string{
+		"Cache-Control": "public, max-age=86400",
 	})
 }
+
+// GetProfileHandle 返回自己的资料。
+func (h *UsersHandle) GetProfileHandle(c *gin.Context) {
+	user, err := h.profileService.Get(sessionUserID(c))
+	if err != nil {
+		c.JSON(profileErrorStatus(err), gin.H{"code": 0, "msg": profileErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": user})
+}
+
+// UpdateProfileHandle 修改显示名称、简介和时区。
+func (h *UsersHandle) UpdateProfileHandle(c *gin.Context) {
+	var req dto.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": err.Error()})
+		return
+	}
+
+	user, err := h.profileService.Update(sessionUserID(c), req.DisplayName, req.Bio, req.Timezone)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), gin.H{"code": 0, "msg": profileErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": user})
+}
+
+// UploadAvatarHandle 接收 multipart 上传的头像，表单字段: file。
+func (h *UsersHandle) UploadAvatarHandle(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, global.MaxAvatarSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 0, "msg": "文件不能为空"})
+		return
+	}
+
+	user, err := h.profileService.SetAvatar(sessionUserID(c), header)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), gin.H{"code": 0, "msg": profileErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": user})
+}
+
+// DeleteAvatarHandle 删除头像。
+func (h *UsersHandle) DeleteAvatarHandle(c *gin.Context) {
+	user, err := h.profileService.RemoveAvatar(sessionUserID(c))
+	if err != nil {
+		c.JSON(profileErrorStatus(err), gin.H{"code": 0, "msg": profileErrorMsg(err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 1, "msg": "success", "data": user})
+}
+
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, logic.UserNotFindError):
+		return http.StatusNotFound
+	case errors.Is(err, logic.FileTooLargeError):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, logic.InvalidProfileError), errors.Is(err, logic.InvalidImageError):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func profileErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, logic.UserNotFindError):
+		return "用户不存在"
+	case errors.Is(err, logic.FileTooLargeError):
+		return "头像过大"
+	case errors.Is(err, logic.InvalidImageError):
+		return "头像必须是图片"
+	case errors.Is(err, logic.InvalidProfileError):
+		return "资料参数错误"
+	default:
+		return "保存失败"
+	}
+}
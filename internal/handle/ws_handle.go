@@ -1,9 +1,12 @@
 package handle
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/l-jessie/test-im/internal/global"
 	"github.com/l-jessie/test-im/internal/logic"
 	types2 "github.com/l-jessie/test-im/internal/model/types"
 
@@ -12,9 +15,12 @@ import (
 )
 
 type WsHandle struct {
-	hub         *types2.Hub
-	chatService *logic.ChatService
-	upgrader    websocket.Upgrader
+	hub                 *types2.Hub
+	chatService         *logic.ChatService
+	receiptService      *logic.ReceiptService
+	announcementService *logic.AnnouncementService
+	deviceService       *logic.DeviceService
+	upgrader            websocket.Upgrader
 }
 
 func matchOrigin(pattern, origin string) bool {
@@ -24,13 +30,19 @@ func matchOrigin(pattern, origin string) bool {
 	return pattern == origin
 }
 
-func NewWsHandle(hub *types2.Hub, chatService *logic.ChatService) *WsHandle {
+func NewWsHandle(hub *types2.Hub, chatService *logic.ChatService, receiptService *logic.ReceiptService, announcementService *logic.AnnouncementService, deviceService *logic.DeviceService) *WsHandle {
 	return &WsHandle{
-		hub:         hub,
-		chatService: chatService,
+		hub:                 hub,
+		chatService:         chatService,
+		receiptService:      receiptService,
+		announcementService: announcementService,
+		deviceService:       deviceService,
 		upgrader: websocket.Upgrader{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
+			ReadBufferSize:  global.ReadBufferSize,
+			WriteBufferSize: global.WriteBufferSize,
+			WriteBufferPool: &sync.Pool{},
+			// 客户端通过 Sec-WebSocket-Protocol 请求 v2 协议及其编码，未请求时使用旧协议
+			Subprotocols: types2.Subprotocols(),
 			CheckOrigin: func(r *http.Request) bool {
 				origin := r.Header.Get("Origin")
 
@@ -65,6 +77,18 @@ func (w *WsHandle) WsHandleFunc(c *gin.Context) {
 		return
 	}
 
+	if err := w.deviceService.Authenticate(userID, deviceID, c.Query("session")); err != nil {
+		msg := "会话无效"
+		if errors.Is(err, logic.DeviceRevokedError) {
+			msg = "设备已被移除"
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code": 0,
+			"msg":  msg,
+		})
+		return
+	}
+
 	connect, err := w.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -75,12 +99,22 @@ func (w *WsHandle) WsHandleFunc(c *gin.Context) {
 	}
 
 	client := types2.NewClient(w.hub, connect, userID, userName, deviceID)
+	client.UserAgent = c.Request.UserAgent()
+	client.IP = c.ClientIP()
+	client.Codec = types2.CodecOf(connect.Subprotocol())
+	registered := make(chan struct{})
 	w.hub.Register <- &types2.RegisterEvent{
 		Client: client,
 		UserId: userID,
+		Done:   registered,
 	}
+	// 等待注册完成后再处理消息，否则紧接着发来的加入房间等命令会找不到连接
+	<-registered
 
 	// Start read and write pumps
-	go client.WritePump()
+	go client.WritePump(w.receiptService.HandleDelivered)
 	go client.ReadPump(w.chatService.HandleMessage)
+
+	// 补发离线期间错过的公告
+	w.announcementService.DeliverPending(client)
 }
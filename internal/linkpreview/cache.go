@@ -0,0 +1,66 @@
+package linkpreview
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type cacheEntry struct {
+	preview  *types.LinkPreview // 抓取失败时为空，同样缓存以免反复请求
+	expireAt time.Time
+}
+
+// CachedFetcher 为另一个 Fetcher 增加内存缓存。
+type CachedFetcher struct {
+	mu sync.Mutex
+
+	fetcher Fetcher
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+func NewCachedFetcher(fetcher Fetcher, ttl time.Duration) *CachedFetcher {
+	return &CachedFetcher{
+		fetcher: fetcher,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+func (f *CachedFetcher) Fetch(ctx context.Context, rawURL string) (*types.LinkPreview, error) {
+	now := time.Now()
+
+	f.mu.Lock()
+	if entry, ok := f.entries[rawURL]; ok && now.Before(entry.expireAt) {
+		f.mu.Unlock()
+		if entry.preview == nil {
+			return nil, CachedFailureError
+		}
+		copied := *entry.preview
+		return &copied, nil
+	}
+	f.mu.Unlock()
+
+	preview, err := f.fetcher.Fetch(ctx, rawURL)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeExpiredNoLock(now)
+	f.entries[rawURL] = &cacheEntry{preview: preview, expireAt: now.Add(f.ttl)}
+	if err != nil {
+		return nil, err
+	}
+	copied := *preview
+	return &copied, nil
+}
+
+func (f *CachedFetcher) removeExpiredNoLock(now time.Time) {
+	for key, entry := range f.entries {
+		if !now.Before(entry.expireAt) {
+			delete(f.entries, key)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+var (
+	BlockedURLError      = errors.New("url is not allowed")
+	BlockedAddressError  = errors.New("address is not allowed")
+	UnsupportedTypeError = errors.New("unsupported content type")
+	CachedFailureError   = errors.New("link preview failed recently")
+)
+
+const maxRedirects = 5
+
+// Fetcher 抓取链接并返回预览信息，不同的抓取方式（例如外部服务）只需实现该接口。
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*types.LinkPreview, error)
+}
+
+type Options struct {
+	Allow    []string // 域名白名单，为空表示不限制；"example.com" 同时匹配其子域名
+	Deny     []string // 域名黑名单，优先于白名单
+	MaxBytes int64
+	Timeout  time.Duration
+}
+
+// HTTPFetcher 直接请求目标页面并解析 Open Graph 元数据。
+// 连接建立时会检查实际解析到的 IP，拒绝内网、回环等地址，防止 SSRF。
+type HTTPFetcher struct {
+	options Options
+	client  *http.Client
+}
+
+func NewHTTPFetcher(options Options) *HTTPFetcher {
+	f := &HTTPFetcher{options: options}
+
+	dialer := &net.Dialer{
+		Timeout: options.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !publicIP(ip) {
+				return BlockedAddressError
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: options.Timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // 不走代理，否则无法校验最终地址
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   options.Timeout,
+			ResponseHeaderTimeout: options.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*types.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "go-im-linkpreview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, UnsupportedTypeError
+	}
+
+	preview := parseHTML(io.LimitReader(resp.Body, f.options.MaxBytes))
+	preview.URL = rawURL
+	preview.Image = resolveImage(resp.Request.URL, preview.Image)
+	return preview, nil
+}
+
+// checkURL 校验协议、端口和域名黑白名单。
+func (f *HTTPFetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return BlockedURLError
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return BlockedURLError
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return BlockedURLError
+	}
+	for _, pattern := range f.options.Deny {
+		if matchHost(pattern, host) {
+			return BlockedURLError
+		}
+	}
+	if len(f.options.Allow) == 0 {
+		return nil
+	}
+	for _, pattern := range f.options.Allow {
+		if matchHost(pattern, host) {
+			return nil
+		}
+	}
+	return BlockedURLError
+}
+
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// publicIP 判断是否是可以访问的公网地址。
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// 100.64.0.0/10 运营商级 NAT 地址，IsPrivate 不包含
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// resolveImage 把相对路径的图片地址转为绝对地址，只保留 http(s) 图片。
+func resolveImage(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+	return abs.String()
+}
@@ -0,0 +1,140 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckURL(t *testing.T) {
+	cases := []struct {
+		name    string
+		options Options
+		url     string
+		wantErr error
+	}{
+		{name: "http", url: "http://example.com/a"},
+		{name: "https with default port", url: "https://example.com:443/a"},
+		{name: "other scheme", url: "file:///etc/passwd", wantErr: BlockedURLError},
+		{name: "ftp", url: "ftp://example.com/", wantErr: BlockedURLError},
+		{name: "other port", url: "http://example.com:8080/", wantErr: BlockedURLError},
+		{name: "no host", url: "http:///a", wantErr: BlockedURLError},
+		{name: "denied", options: Options{Deny: []string{"evil.com"}}, url: "http://evil.com/", wantErr: BlockedURLError},
+		{name: "denied subdomain", options: Options{Deny: []string{"evil.com"}}, url: "http://a.EVIL.com./", wantErr: BlockedURLError},
+		{name: "suffix is not a subdomain", options: Options{Deny: []string{"evil.com"}}, url: "http://notevil.com/"},
+		{name: "allowed", options: Options{Allow: []string{"example.com"}}, url: "http://www.example.com/"},
+		{name: "not allowed", options: Options{Allow: []string{"example.com"}}, url: "http://other.com/", wantErr: BlockedURLError},
+		{
+			name:    "deny wins over allow",
+			options: Options{Allow: []string{"example.com"}, Deny: []string{"internal.example.com"}},
+			url:     "http://internal.example.com/",
+			wantErr: BlockedURLError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if err := NewHTTPFetcher(tc.options).checkURL(u); !errors.Is(err, tc.wantErr) {
+				t.Errorf("checkURL(%s) = %v, want %v", tc.url, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "8.8.8.8", want: true},
+		{ip: "2606:4700::1111", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"}, // 云主机元数据服务
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "224.0.0.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ip, func(t *testing.T) {
+			if got := publicIP(net.ParseIP(tc.ip)); got != tc.want {
+				t.Errorf("publicIP(%s) = %v, want %v", tc.ip, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	cases := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		// 域名通过校验，但解析到的地址在连接时被拦截
+		{name: "name resolving to loopback", url: "http://localhost/", wantErr: BlockedAddressError},
+		{name: "loopback literal", url: "http://127.0.0.1/", wantErr: BlockedAddressError},
+		{name: "metadata service", url: "http://169.254.169.254/latest/meta-data/", wantErr: BlockedAddressError},
+		{name: "ipv6 loopback", url: "http://[::1]/", wantErr: BlockedAddressError},
+		{name: "non default port", url: "http://127.0.0.1:6379/", wantErr: BlockedURLError},
+	}
+
+	f := NewHTTPFetcher(Options{MaxBytes: 1 << 20, Timeout: 2 * time.Second})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := f.Fetch(context.Background(), tc.url); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Fetch(%s) = %v, want %v", tc.url, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectIsChecked(t *testing.T) {
+	cases := []struct {
+		name    string
+		target  string
+		via     int
+		wantErr error
+	}{
+		{name: "public", target: "https://example.com/next"},
+		{name: "other scheme", target: "file:///etc/passwd", wantErr: BlockedURLError},
+		{name: "internal port", target: "http://10.0.0.1:8080/", wantErr: BlockedURLError},
+		{name: "denied host", target: "http://internal.example.com/", wantErr: BlockedURLError},
+		{name: "too many redirects", target: "https://example.com/next", via: maxRedirects},
+	}
+
+	f := NewHTTPFetcher(Options{Deny: []string{"internal.example.com"}})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.target, nil)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			via := make([]*http.Request, tc.via)
+			err = f.client.CheckRedirect(req, via)
+			if tc.via >= maxRedirects {
+				if err == nil {
+					t.Errorf("redirect after %d hops allowed", tc.via)
+				}
+				return
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("redirect to %s = %v, want %v", tc.target, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package linkpreview
+
+import (
+	"io"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+
+	"golang.org/x/net/html"
+)
+
+const maxFieldRunes = 300
+
+// parseHTML 从页面头部提取 Open Graph 元数据，没有时退回到 <title> 和 description。
+func parseHTML(r io.Reader) *types.LinkPreview {
+	preview := &types.LinkPreview{}
+	var title, description string
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finish(preview, title, description)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				// 元数据都在 head 中，不需要继续解析正文
+				return finish(preview, title, description)
+			case "title":
+				if tokenizer.Next() == html.TextToken {
+					title = string(tokenizer.Text())
+				}
+			case "meta":
+				key, content := metaAttrs(token)
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image":
+					preview.Image = content
+				case "og:site_name":
+					preview.SiteName = content
+				case "description":
+					description = content
+				}
+			}
+		}
+	}
+}
+
+func finish(preview *types.LinkPreview, title, description string) *types.LinkPreview {
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	preview.Title = clean(preview.Title)
+	preview.Description = clean(preview.Description)
+	preview.SiteName = clean(preview.SiteName)
+	preview.Image = strings.TrimSpace(preview.Image)
+	return preview
+}
+
+func metaAttrs(token html.Token) (string, string) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "property", "name":
+			key = strings.ToLower(attr.Val)
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+func clean(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > maxFieldRunes {
+		return string(runes[:maxFieldRunes]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,19 @@
+package logic
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// canAccessConversation 判断用户是否是会话的参与者：房间成员、群组成员或私聊双方之一。
+func canAccessConversation(hub *types.Hub, userID, conversationID string) bool {
+	if roomID, ok := types.ConversationRoomID(conversationID); ok {
+		return hub.InRoom(roomID, userID)
+	}
+	if groupID, ok := types.ConversationGroupID(conversationID); ok {
+		return hub.InGroup(groupID, userID)
+	}
+	if a, b, ok := types.ConversationPeers(conversationID); ok {
+		return a == userID || b == userID
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/markdown"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	InvalidAnnouncementError = errors.New("invalid announcement")
+	InvalidTargetError       = errors.New("announcement needs exactly one target")
+)
+
+// AnnouncementService 发布管理员公告。公告会被保存，离线用户在下次连接时补收。
+type AnnouncementService struct {
+	hub           *types.Hub
+	announcements *store.AnnouncementStore
+}
+
+func NewAnnouncementService(hub *types.Hub, announcements *store.AnnouncementStore) *AnnouncementService {
+	return &AnnouncementService{
+		hub:           hub,
+		announcements: announcements,
+	}
+}
+
+// Create 发布公告，推送给当前在线的目标用户，其余用户在上线时补发。
+func (s *AnnouncementService) Create(req *dto.CreateAnnouncementRequest) (*dto.AnnouncementResponse, error) {
+	targets := 0
+	if req.All {
+		targets++
+	}
+	if len(req.RoomIDs) > 0 {
+		targets++
+	}
+	if len(req.UserIDs) > 0 {
+		targets++
+	}
+	if targets != 1 {
+		return nil, InvalidTargetError
+	}
+
+	payload, err := announcementPayload(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &store.AnnouncementRecord{
+		Announcement: &types.Announcement{
+			ID:         utils.GenerateUUID(),
+			Payload:    payload,
+			CreateTime: time.Now().Unix(),
+		},
+		CreatorID: req.UserID,
+		All:       req.All,
+		RoomIDs:   req.RoomIDs,
+		UserIDs:   req.UserIDs,
+	}
+	var recipients []string
+	if !req.All {
+		// 房间成员关系依赖连接，只能在发布时展开为当前成员
+		recipients = req.UserIDs
+		if len(req.RoomIDs) > 0 {
+			recipients = s.hub.RoomUserIDs(req.RoomIDs)
+		}
+		record.Recipients = make(map[string]bool, len(recipients))
+		for _, userID := range recipients {
+			record.Recipients[userID] = true
+		}
+	}
+	s.announcements.Save(record)
+
+	s.deliver(record, recipients)
+	return announcementResponse(record), nil
+}
+
+// deliver 把公告推送给在线的目标用户，并记录为已投递。
+// 用户恰好在此期间连接时可能收到两次，客户端按公告ID去重。
+func (s *AnnouncementService) deliver(record *store.AnnouncementRecord, recipients []string) {
+	marshal, err := json.Marshal(record.Announcement)
+	if err != nil {
+		log.Printf("announcement json marshal error: %v", err)
+		return
+	}
+	event := types.NewMessageEventPayload(types.AnnouncementPublished, marshal)
+
+	online := s.hub.OnlineUserIDs()
+	if record.All {
+		s.announcements.MarkDelivered(record.Announcement.ID, online)
+		s.hub.Broadcast <- types.NewMessageEvent(types.MessageTypeGlobal, event)
+		return
+	}
+
+	var delivered []string
+	for _, userID := range online {
+		if record.Recipients[userID] {
+			delivered = append(delivered, userID)
+		}
+	}
+	s.announcements.MarkDelivered(record.Announcement.ID, delivered)
+	for _, userID := range delivered {
+		s.hub.Broadcast <- types.NewSystemMessage(userID, event)
+	}
+}
+
+// DeliverPending 向刚连接的设备补发用户离线期间错过的公告。
+func (s *AnnouncementService) DeliverPending(client *types.Client) {
+	for _, announcement := range s.announcements.TakePending(client.UserId, global.MaxPendingAnnouncements) {
+		marshal, err := json.Marshal(announcement)
+		if err != nil {
+			log.Printf("announcement json marshal error: %v", err)
+			continue
+		}
+		message := types.NewSystemMessage(client.UserId, types.NewMessageEventPayload(types.AnnouncementPublished, marshal))
+		messageMarshal, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("announcement json marshal error: %v", err)
+			continue
+		}
+		if err := client.SendMessage(messageMarshal, message); err != nil {
+			log.Printf("deliver announcement error: UserID: %s, %v", client.UserId, err)
+		}
+	}
+}
+
+// ListByUser 返回发给用户的所有公告。
+func (s *AnnouncementService) ListByUser(userID string) []*types.Announcement {
+	return s.announcements.ListByUser(userID)
+}
+
+// List 返回所有公告及其目标，供管理员查看。
+func (s *AnnouncementService) List() []*dto.AnnouncementResponse {
+	records := s.announcements.List()
+	result := make([]*dto.AnnouncementResponse, 0, len(records))
+	for _, record := range records {
+		result = append(result, announcementResponse(record))
+	}
+	return result
+}
+
+// announcementPayload 校验公告内容，只支持文本和 Markdown。
+func announcementPayload(payload *types.Payload) (*types.Payload, error) {
+	if payload == nil {
+		return nil, InvalidAnnouncementError
+	}
+
+	var content string
+	if err := json.Unmarshal(payload.Content, &content); err != nil || content == "" {
+		return nil, InvalidAnnouncementError
+	}
+
+	result := &types.Payload{Type: payload.Type, Content: payload.Content}
+	switch payload.Type {
+	case types.PayloadTypeText:
+	case types.PayloadTypeMarkdown:
+		html, err := markdown.Render(content)
+		if err != nil {
+			return nil, err
+		}
+		result.HTML = html
+	default:
+		return nil, InvalidAnnouncementError
+	}
+	return result, nil
+}
+
+func announcementResponse(record *store.AnnouncementRecord) *dto.AnnouncementResponse {
+	return &dto.AnnouncementResponse{
+		Announcement:   record.Announcement,
+		CreatorID:      record.CreatorID,
+		All:            record.All,
+		RoomIDs:        record.RoomIDs,
+		UserIDs:        record.UserIDs,
+		RecipientCount: len(record.Recipients),
+	}
+}
@@ -2,29 +2,436 @@ package logic
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"sort"
+	"time"
 
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/markdown"
 	types2 "github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	ForwardNotAllowedError     = errors.New("message can not be forwarded")
+	InvalidMarkdownError       = errors.New("invalid markdown content")
+	MessageTypeNotAllowedError = errors.New("message type not allowed")
 )
 
 type ChatService struct {
-	hub *types2.Hub
+	hub             *types2.Hub
+	messages        *store.MessageStore
+	conversations   *store.ConversationStore
+	receiptService  *ReceiptService
+	fileService     *FileService
+	pollService     *PollService
+	linkPreviews    *LinkPreviewService
+	privacyService  *PrivacyService
+	presenceService *PresenceService
+	roomService     *RoomService
 }
 
-func NewChatService(hub *types2.Hub) *ChatService {
+func NewChatService(hub *types2.Hub, messages *store.MessageStore, conversations *store.ConversationStore, receiptService *ReceiptService, fileService *FileService, pollService *PollService, linkPreviews *LinkPreviewService, privacyService *PrivacyService, presenceService *PresenceService, roomService *RoomService) *ChatService {
 	return &ChatService{
-		hub: hub,
+		hub:             hub,
+		messages:        messages,
+		conversations:   conversations,
+		receiptService:  receiptService,
+		fileService:     fileService,
+		pollService:     pollService,
+		linkPreviews:    linkPreviews,
+		privacyService:  privacyService,
+		presenceService: presenceService,
+		roomService:     roomService,
 	}
 }
 
+// HandleMessage 处理客户端发来的一帧数据。
+// v2 客户端的请求处理完成后会收到带有请求ID的 ack 或 error 应答，旧客户端只会收到 error。
 func (c *ChatService) HandleMessage(client *types2.Client, messageByte []byte) {
-	var message *types2.Message
-	err := json.Unmarshal(messageByte, &message)
+	message, requestID, err := client.DecodeMessage(messageByte)
 	if err != nil {
 		log.Printf("message unmarshal error: %v", err)
+		if client.Versioned() {
+			c.replyError(client, requestID, &types2.Message{}, err)
+		}
+		return
+	}
+	if message == nil {
+		return
+	}
+
+	// 已读回执只更新水位，不进行转发
+	if message.Type == types2.MessageTypeReceipt {
+		if err := c.receiptService.MarkRead(client, message.Receipt); err != nil {
+			log.Printf("mark read error: UserID: %s, %v", client.UserId, err)
+			c.replyV2Error(client, requestID, message, err)
+			return
+		}
+		_ = client.SendAck(requestID, nil)
+		return
+	}
+
+	if message.Type == types2.MessageTypeVote {
+		if err := c.pollService.Vote(client, message.Vote); err != nil {
+			log.Printf("vote error: UserID: %s, %v", client.UserId, err)
+			c.replyV2Error(client, requestID, message, err)
+			return
+		}
+		_ = client.SendAck(requestID, nil)
+		return
+	}
+
+	if message.Type == types2.MessageTypeSubscribe {
+		if err := c.subscribe(client, message.Subscribe); err != nil {
+			c.replyError(client, requestID, message, err)
+			return
+		}
+		_ = client.SendAck(requestID, nil)
+		return
+	}
+
+	if message.Type == types2.MessageTypeCreateRoom || message.Type == types2.MessageTypeJoinRoom || message.Type == types2.MessageTypeLeaveRoom {
+		c.handleRoomCommand(client, requestID, message)
+		return
+	}
+
+	// 客户端只能发送会话消息，全局、系统等消息只能由服务端产生
+	if message.Type != types2.MessageTypeRoom && message.Type != types2.MessageTypeUser && message.Type != types2.MessageTypeGroup {
+		c.replyError(client, requestID, message, MessageTypeNotAllowedError)
 		return
 	}
 
 	message.From = client.UserId
+	message.Forwarded = nil
+	if err := c.Send(message); err != nil {
+		c.replyError(client, requestID, message, err)
+		return
+	}
+	_ = client.SendAck(requestID, &types2.Ack{MessageID: message.ID, Seq: message.Seq, Timestamp: message.Timestamp})
+}
+
+// handleRoomCommand 执行房间命令，v2 客户端收到带有房间的 ack，
+// 旧客户端收到与命令同类型、Room 为结果的消息。
+func (c *ChatService) handleRoomCommand(client *types2.Client, requestID string, message *types2.Message) {
+	room, err := c.roomService.Handle(client, message.Type, message.RoomCommand)
+	if err != nil {
+		if message.RoomCommand != nil {
+			message.To = message.RoomCommand.RoomID
+		}
+		c.replyError(client, requestID, message, err)
+		return
+	}
+
+	if client.Versioned() {
+		_ = client.SendAck(requestID, room)
+		return
+	}
+	result := &types2.Message{
+		Type:      message.Type,
+		To:        client.UserId,
+		Room:      room,
+		Timestamp: time.Now().Unix(),
+	}
+	marshal, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("room result json marshal error: %v", err)
+		return
+	}
+	_ = client.SendMessage(marshal, result)
+}
+
+// subscribe 修改连接的订阅：在线状态订阅和是否接收房间增量事件。
+func (c *ChatService) subscribe(client *types2.Client, subscription *types2.Subscription) error {
+	if subscription == nil {
+		return InvalidSubscriptionError
+	}
+	if subscription.Rooms != nil {
+		client.SetRoomDeltas(*subscription.Rooms)
+	}
+	if subscription.HasPresence() {
+		return c.presenceService.Subscribe(client, subscription)
+	}
+	return nil
+}
+
+// replyV2Error 只向 v2 客户端应答错误，旧客户端的回执和投票失败时不会收到错误帧。
+func (c *ChatService) replyV2Error(client *types2.Client, requestID string, message *types2.Message, err error) {
+	if client.Versioned() {
+		c.replyError(client, requestID, message, err)
+	}
+}
+
+// replyError 把消息被拒绝的原因发回给发送消息的设备。
+func (c *ChatService) replyError(client *types2.Client, requestID string, message *types2.Message, err error) {
+	log.Printf("send message error: UserID: %s, %v", client.UserId, err)
+
+	reply := types2.NewErrorMessage(client.UserId, &types2.ErrorInfo{
+		Code:    errorCode(err),
+		Message: err.Error(),
+		Type:    message.Type,
+		Target:  message.To,
+	})
+	marshal, err := json.Marshal(reply)
+	if err != nil {
+		log.Printf("error message json marshal error: %v", err)
+		return
+	}
+	_ = client.SendReply(requestID, marshal, reply)
+}
+
+// errorCode 返回错误帧中供客户端判断的错误码。
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, DMNotAllowedError):
+		return "dm_not_allowed"
+	case errors.Is(err, BlockedError):
+		return "blocked"
+	case errors.Is(err, NotParticipantError):
+		return "not_participant"
+	case errors.Is(err, MessageTypeNotAllowedError):
+		return "type_not_allowed"
+	case errors.Is(err, FileNotFindError):
+		return "file_not_found"
+	case errors.Is(err, TooManySubscriptionsError):
+		return "too_many_subscriptions"
+	case errors.Is(err, SubscriptionForbiddenError):
+		return "subscription_forbidden"
+	case errors.Is(err, types2.RoomNotFindError):
+		return "room_not_found"
+	case errors.Is(err, types2.RoomPasswordError):
+		return "wrong_password"
+	case errors.Is(err, types2.NotRoomMemberError):
+		return "not_room_member"
+	case errors.Is(err, types2.ClientNotFindError):
+		return "not_connected"
+	case errors.Is(err, types2.UnsupportedVersionError):
+		return "unsupported_version"
+	case errors.Is(err, types2.UnsupportedOperationError):
+		return "unsupported_operation"
+	default:
+		return "invalid_message"
+	}
+}
+
+// Send 投递一条消息，message.From 必须由调用方设置为可信的发送者。
+// 会话消息由服务端分配ID并保存，供回执、会话列表等引用。
+func (c *ChatService) Send(message *types2.Message) error {
+	message.Receipt = nil
+	message.Vote = nil
+
+	if conversationID := types2.ConversationIDOf(message); conversationID != "" {
+		// 群组是私有的，只有成员可以发送消息；房间消息同样只能由房间成员发送
+		if message.Type == types2.MessageTypeGroup && !c.hub.InGroup(message.To, message.From) {
+			return NotParticipantError
+		}
+		if message.Type == types2.MessageTypeRoom && !canAccessConversation(c.hub, message.From, conversationID) {
+			return NotParticipantError
+		}
+		if message.Type == types2.MessageTypeUser {
+			if err := c.privacyService.CheckDM(message.From, message.To); err != nil {
+				return err
+			}
+		}
+		// 图片、文件消息只能引用本会话中上传的文件
+		if err := c.fileService.AttachFile(conversationID, message.Payload); err != nil {
+			return err
+		}
+
+		message.ID = utils.GenerateUUID()
+		message.Timestamp = time.Now().Unix()
+		if err := c.renderPayload(conversationID, message); err != nil {
+			return err
+		}
+		c.parseMentions(conversationID, message)
+		c.applyTTL(conversationID, message)
+		c.messages.Save(conversationID, message)
+		defer c.linkPreviews.Unfurl(message)
+		defer c.notifyMentions(message)
+	}
+
 	c.hub.Broadcast <- message
+	return nil
+}
+
+// parseMentions 从房间和群组消息的文本中找出被提及的会话成员，@ 之后可以是用户ID或用户名。
+func (c *ChatService) parseMentions(conversationID string, message *types2.Message) {
+	payload := message.Payload
+	if payload == nil || (payload.Type != types2.PayloadTypeText && payload.Type != types2.PayloadTypeMarkdown) {
+		return
+	}
+	var memberIDs []string
+	if roomID, ok := types2.ConversationRoomID(conversationID); ok {
+		memberIDs = c.hub.RoomUserIDs([]string{roomID})
+	} else if groupID, ok := types2.ConversationGroupID(conversationID); ok {
+		if group, ok := c.hub.Group(groupID); ok {
+			memberIDs = group.MemberIDs()
+		}
+	}
+	if len(memberIDs) == 0 {
+		return
+	}
+
+	var text string
+	if err := json.Unmarshal(payload.Content, &text); err != nil {
+		return
+	}
+	tokens := types2.MentionTokens(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	mentioned := make(map[string]bool)
+	for _, memberID := range memberIDs {
+		name, _ := c.hub.UserName(memberID)
+		for _, token := range tokens {
+			if token == memberID || (name != "" && token == name) {
+				mentioned[memberID] = true
+				break
+			}
+		}
+	}
+	for memberID := range mentioned {
+		payload.Mentions = append(payload.Mentions, memberID)
+	}
+	sort.Strings(payload.Mentions)
+}
+
+// notifyMentions 通知被提及的用户，屏蔽了发送者的用户不会收到通知。
+func (c *ChatService) notifyMentions(message *types2.Message) {
+	if message.Payload == nil || len(message.Payload.Mentions) == 0 {
+		return
+	}
+	marshal, err := json.Marshal(types2.NewMention(message))
+	if err != nil {
+		log.Printf("mention json marshal error: %v", err)
+		return
+	}
+	for _, userID := range message.Payload.Mentions {
+		if userID == message.From || c.privacyService.IsBlocked(userID, message.From) {
+			continue
+		}
+		c.hub.Broadcast <- types2.NewSystemMessage(userID, types2.NewMessageEventPayload(types2.Mentioned, marshal))
+	}
+}
+
+// Forward 把一条已保存的消息复制到目标房间或私聊，保留原作者和来源信息。
+// 转发者必须能读取原消息所在的会话，并且能向目标会话发送消息。
+func (c *ChatService) Forward(userID, messageID string, targetType types2.MessageType, to string) (*types2.Message, error) {
+	source, ok := c.messages.Get(messageID)
+	if !ok {
+		return nil, MessageNotFindError
+	}
+	sourceConversationID := types2.ConversationIDOf(source)
+	if !canAccessConversation(c.hub, userID, sourceConversationID) {
+		return nil, NotParticipantError
+	}
+	// 阅后即焚的消息不允许被转发出去
+	if source.ExpireAt > 0 || source.Payload == nil {
+		return nil, ForwardNotAllowedError
+	}
+
+	message := types2.NewMessage(targetType, nil, userID, to)
+	targetConversationID := types2.ConversationIDOf(message)
+	if targetConversationID == "" || to == "" {
+		return nil, InvalidMessageTypeError
+	}
+	if !canAccessConversation(c.hub, userID, targetConversationID) {
+		return nil, NotParticipantError
+	}
+
+	// 发送过程会修改 payload，使用副本避免影响已保存的原消息
+	payload := source.Payload.Clone()
+	if payload.FileID != "" {
+		fileID, err := c.fileService.CopyToConversation(payload.FileID, targetConversationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		payload.FileID = fileID
+	}
+	message.Payload = payload
+
+	// 转发的转发仍然指向最初的来源
+	message.Forwarded = source.Forwarded
+	if message.Forwarded == nil {
+		message.Forwarded = &types2.ForwardInfo{
+			MessageID:      source.ID,
+			From:           source.From,
+			ConversationID: sourceConversationID,
+			Timestamp:      source.Timestamp,
+		}
+	}
+
+	if err := c.Send(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// renderPayload 处理需要服务端解析的消息内容：投票和 Markdown。
+func (c *ChatService) renderPayload(conversationID string, message *types2.Message) error {
+	if message.Payload == nil {
+		return nil
+	}
+	// HTML 和提及列表只能由服务端生成
+	message.Payload.HTML = ""
+	message.Payload.Mentions = nil
+
+	switch message.Payload.Type {
+	case types2.PayloadTypePoll:
+		return c.pollService.Create(conversationID, message)
+	case types2.PayloadTypeMarkdown:
+		html, err := renderMarkdown(message.Payload.Content)
+		if err != nil {
+			return err
+		}
+		message.Payload.HTML = html
+	}
+	return nil
+}
+
+// ValidatePayload 按发送时的规则校验消息内容，但不保存文件引用和投票。
+// 定时消息在创建时调用，避免错误的内容到发送时才失败。
+func (c *ChatService) ValidatePayload(conversationID string, payload *types2.Payload) error {
+	payload = payload.Clone()
+	if err := c.fileService.AttachFile(conversationID, payload); err != nil {
+		return err
+	}
+
+	switch payload.Type {
+	case types2.PayloadTypePoll:
+		return c.pollService.Validate(payload)
+	case types2.PayloadTypeMarkdown:
+		_, err := renderMarkdown(payload.Content)
+		return err
+	}
+	return nil
+}
+
+func renderMarkdown(content json.RawMessage) (string, error) {
+	var source string
+	if err := json.Unmarshal(content, &source); err != nil {
+		return "", InvalidMarkdownError
+	}
+	return markdown.Render(source)
+}
+
+// applyTTL 计算消息的过期时间，消息未指定存活时间时使用会话的默认设置。
+func (c *ChatService) applyTTL(conversationID string, message *types2.Message) {
+	ttl := message.TTL
+	if ttl <= 0 {
+		ttl = c.conversations.Settings(conversationID).DisappearingSeconds
+	}
+	if maxTTL := int64(global.MaxMessageTTL / time.Second); ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	message.TTL = 0
+	message.ExpireAt = 0
+	if ttl > 0 {
+		message.TTL = ttl
+		message.ExpireAt = message.Timestamp + ttl
+	}
 }
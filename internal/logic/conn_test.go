@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// testConn 是通过真实 websocket 连接注册到 hub 的设备，用来读取服务端发给该设备的帧。
+type testConn struct {
+	hub    *types.Hub
+	client *types.Client   // 服务端一侧
+	conn   *websocket.Conn // 客户端一侧
+}
+
+var syncSeq atomic.Int64
+
+func newTestHub() *types.Hub {
+	hub := types.NewHub()
+	go hub.Run()
+	return hub
+}
+
+// registerTestClient 注册一个没有 websocket 连接的设备，发给它的帧留在发送队列中。
+func registerTestClient(hub *types.Hub, userID, deviceID string) *types.Client {
+	client := types.NewClient(hub, nil, userID, userID, deviceID)
+	done := make(chan struct{})
+	hub.Register <- &types.RegisterEvent{UserId: userID, Client: client, Done: done}
+	<-done
+	return client
+}
+
+func newTestConn(t *testing.T, hub *types.Hub, userID, deviceID string) *testConn {
+	t.Helper()
+
+	clients := make(chan *types.Client, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := types.NewClient(hub, conn, userID, userID, deviceID)
+		done := make(chan struct{})
+		hub.Register <- &types.RegisterEvent{UserId: userID, Client: client, Done: done}
+		<-done
+		go client.WritePump(nil)
+		clients <- client
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &testConn{hub: hub, client: <-clients, conn: conn}
+}
+
+// frames 返回连接目前为止收到的所有帧。
+// 它通过 hub 给该用户发送一帧标记，hub 按顺序投递，读到标记时之前发出的帧都已经到达。
+// 同一用户的其他设备也会收到标记，读取时会被跳过。
+func (c *testConn) frames(t *testing.T) []*types.Message {
+	t.Helper()
+
+	marker := "sync-" + strconv.FormatInt(syncSeq.Add(1), 10)
+	c.hub.Broadcast <- &types.Message{ID: marker, Type: types.MessageTypeSystem, To: c.client.UserId}
+
+	var messages []*types.Message
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var message types.Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("unmarshal frame: %v", err)
+		}
+		if message.ID == marker {
+			return messages
+		}
+		if strings.HasPrefix(message.ID, "sync-") {
+			continue
+		}
+		messages = append(messages, &message)
+	}
+}
+
+// events 返回连接收到的指定类型的事件数据。
+func (c *testConn) events(t *testing.T, eventType types.MessageEventType) []json.RawMessage {
+	t.Helper()
+
+	var result []json.RawMessage
+	for _, message := range c.frames(t) {
+		if message.MessageEvent != nil && message.MessageEvent.Type == eventType {
+			result = append(result, message.MessageEvent.Data)
+		}
+	}
+	return result
+}
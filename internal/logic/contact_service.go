@@ -0,0 +1,191 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+const friendRequestMaxRunes = 100
+
+var (
+	InvalidFriendRequestError = errors.New("invalid friend request")
+	AlreadyContactError       = errors.New("already a contact")
+	NotContactError           = errors.New("not a contact")
+	FriendRequestNotFindError = store.FriendRequestNotFindError
+	FriendRequestHandledError = store.FriendRequestHandledError
+)
+
+type ContactService struct {
+	hub              *types.Hub
+	contacts         *store.ContactStore
+	privacy          *store.PrivacyStore
+	presenceService  *PresenceService
+	directoryService *DirectoryService
+}
+
+func NewContactService(hub *types.Hub, contacts *store.ContactStore, privacy *store.PrivacyStore, presenceService *PresenceService, directoryService *DirectoryService) *ContactService {
+	return &ContactService{
+		hub:              hub,
+		contacts:         contacts,
+		privacy:          privacy,
+		presenceService:  presenceService,
+		directoryService: directoryService,
+	}
+}
+
+// SendRequest 向 to 发送好友请求。对方已经向自己发出请求时直接成为联系人。
+func (s *ContactService) SendRequest(from, to, message string) (*store.FriendRequest, error) {
+	message = strings.TrimSpace(message)
+	if from == "" || to == "" || from == to || len([]rune(message)) > friendRequestMaxRunes {
+		return nil, InvalidFriendRequestError
+	}
+	if s.privacy.IsBlocked(to, from) {
+		return nil, BlockedError
+	}
+	if s.contacts.IsContact(from, to) {
+		return nil, AlreadyContactError
+	}
+	if request, ok := s.contacts.PendingBetween(from, to); ok {
+		return request, nil
+	}
+	if request, ok := s.contacts.PendingBetween(to, from); ok {
+		return s.resolve(request, store.FriendRequestAccepted)
+	}
+
+	now := time.Now().Unix()
+	request := &store.FriendRequest{
+		ID:         utils.GenerateUUID(),
+		From:       from,
+		To:         to,
+		Message:    message,
+		Status:     store.FriendRequestPending,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	s.contacts.SaveRequest(request)
+	s.notifyRequest(request)
+	return request, nil
+}
+
+// Accept 接受好友请求，只有请求的接收者可以操作。
+func (s *ContactService) Accept(userID, requestID string) (*store.FriendRequest, error) {
+	request, ok := s.contacts.GetRequest(requestID)
+	if !ok || request.To != userID {
+		return nil, FriendRequestNotFindError
+	}
+	return s.resolve(request, store.FriendRequestAccepted)
+}
+
+// Decline 拒绝好友请求，只有请求的接收者可以操作。
+func (s *ContactService) Decline(userID, requestID string) (*store.FriendRequest, error) {
+	request, ok := s.contacts.GetRequest(requestID)
+	if !ok || request.To != userID {
+		return nil, FriendRequestNotFindError
+	}
+	return s.resolve(request, store.FriendRequestDeclined)
+}
+
+// Cancel 撤回好友请求，只有请求的发送者可以操作。
+func (s *ContactService) Cancel(userID, requestID string) (*store.FriendRequest, error) {
+	request, ok := s.contacts.GetRequest(requestID)
+	if !ok || request.From != userID {
+		return nil, FriendRequestNotFindError
+	}
+	return s.resolve(request, store.FriendRequestCancelled)
+}
+
+func (s *ContactService) resolve(request *store.FriendRequest, status store.FriendRequestStatus) (*store.FriendRequest, error) {
+	request, err := s.contacts.Resolve(request.ID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyRequest(request)
+	if status == store.FriendRequestAccepted {
+		s.notifyContactsChanged(request.From, request.To)
+		s.notifyContactsChanged(request.To, request.From)
+	}
+	return request, nil
+}
+
+// PendingRequests 返回用户收到和发出的待处理请求。
+func (s *ContactService) PendingRequests(userID string) []*store.FriendRequest {
+	return s.contacts.PendingRequests(userID)
+}
+
+// Contacts 返回联系人列表和在线状态，在线的排在前面。
+func (s *ContactService) Contacts(userID string) []*dto.ContactResponse {
+	contacts := s.contacts.Contacts(userID)
+	result := make([]*dto.ContactResponse, 0, len(contacts))
+	for contactID, since := range contacts {
+		name := s.directoryService.Name(contactID)
+		presence := s.presenceService.Presence(contactID)
+		result = append(result, &dto.ContactResponse{
+			ID:       contactID,
+			Name:     name,
+			Online:   presence.Visible(),
+			Presence: presence,
+			Since:    since,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Online != result[j].Online {
+			return result[i].Online
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// RemoveContact 双向解除联系人关系。
+func (s *ContactService) RemoveContact(userID, contactID string) error {
+	if !s.contacts.RemoveContact(userID, contactID) {
+		return NotContactError
+	}
+	s.notifyContactsChanged(userID, contactID)
+	s.notifyContactsChanged(contactID, userID)
+	return nil
+}
+
+// ContactIDs 返回用户所有联系人的ID。
+func (s *ContactService) ContactIDs(userID string) []string {
+	contacts := s.contacts.Contacts(userID)
+	result := make([]string, 0, len(contacts))
+	for contactID := range contacts {
+		result = append(result, contactID)
+	}
+	return result
+}
+
+// IsContact 判断两个用户是否互为联系人。
+func (s *ContactService) IsContact(userID, otherID string) bool {
+	return s.contacts.IsContact(userID, otherID)
+}
+
+// notifyRequest 通知请求双方请求状态的变化。
+func (s *ContactService) notifyRequest(request *store.FriendRequest) {
+	marshal, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("friend request json marshal error: %v", err)
+		return
+	}
+	event := types.NewMessageEventPayload(types.FriendRequestUpdated, marshal)
+	s.hub.Broadcast <- types.NewSystemMessage(request.From, event)
+	s.hub.Broadcast <- types.NewSystemMessage(request.To, event)
+}
+
+// notifyContactsChanged 通知 userID 与 contactID 的联系人关系发生变化。
+func (s *ContactService) notifyContactsChanged(userID, contactID string) {
+	marshal, _ := json.Marshal(contactID)
+	s.hub.Broadcast <- types.NewSystemMessage(userID, types.NewMessageEventPayload(types.ContactsChanged, marshal))
+}
@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+const previewMaxRunes = 50
+
+var (
+	InvalidDisappearingError = errors.New("invalid disappearing seconds")
+)
+
+type ConversationService struct {
+	hub              *types.Hub
+	messages         *store.MessageStore
+	receipts         *store.ReceiptStore
+	conversations    *store.ConversationStore
+	directoryService *DirectoryService
+}
+
+func NewConversationService(hub *types.Hub, messages *store.MessageStore, receipts *store.ReceiptStore, conversations *store.ConversationStore, directoryService *DirectoryService) *ConversationService {
+	return &ConversationService{
+		hub:              hub,
+		messages:         messages,
+		receipts:         receipts,
+		conversations:    conversations,
+		directoryService: directoryService,
+	}
+}
+
+// ListConversations 返回用户所在的房间、群组和私聊，按最后一条消息时间倒序排列。
+func (s *ConversationService) ListConversations(userID string) []*dto.ConversationResponse {
+	conversationIDs := make(map[string]bool)
+	for _, conversationID := range s.messages.Conversations(userID) {
+		conversationIDs[conversationID] = true
+	}
+	for _, roomID := range s.hub.UserRoomIDs(userID) {
+		conversationIDs[types.RoomConversationID(roomID)] = true
+	}
+	for _, group := range s.hub.UserGroups(userID) {
+		conversationIDs[types.GroupConversationID(group.ID)] = true
+	}
+
+	result := make([]*dto.ConversationResponse, 0, len(conversationIDs))
+	for conversationID := range conversationIDs {
+		result = append(result, s.conversation(userID, conversationID))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return lastMessageTime(result[i]) > lastMessageTime(result[j])
+	})
+	return result
+}
+
+func (s *ConversationService) conversation(userID, conversationID string) *dto.ConversationResponse {
+	resp := &dto.ConversationResponse{ID: conversationID}
+
+	if roomID, ok := types.ConversationRoomID(conversationID); ok {
+		resp.Type = types.MessageTypeRoom
+		resp.TargetID = roomID
+		resp.Name, _ = s.hub.RoomName(roomID)
+	} else if groupID, ok := types.ConversationGroupID(conversationID); ok {
+		resp.Type = types.MessageTypeGroup
+		resp.TargetID = groupID
+		if group, ok := s.hub.Group(groupID); ok {
+			resp.Name = group.Name
+		}
+	} else if a, b, ok := types.ConversationPeers(conversationID); ok {
+		resp.Type = types.MessageTypeUser
+		resp.TargetID = a
+		if a == userID {
+			resp.TargetID = b
+		}
+		resp.Name = s.directoryService.Name(resp.TargetID)
+	}
+
+	if last, ok := s.messages.Last(conversationID); ok {
+		resp.LastMessage = previewMessage(last)
+	}
+	resp.LastReadSeq = s.receipts.ReadSeq(conversationID, userID)
+	resp.UnreadCount = s.messages.CountUnread(conversationID, userID, resp.LastReadSeq)
+	return resp
+}
+
+// Settings 返回会话设置，只有会话参与者可以查看。
+func (s *ConversationService) Settings(userID, conversationID string) (*store.ConversationSettings, error) {
+	if !canAccessConversation(s.hub, userID, conversationID) {
+		return nil, NotParticipantError
+	}
+	return s.conversations.Settings(conversationID), nil
+}
+
+// SetDisappearing 修改会话的阅后即焚默认时间，并通知会话所有参与者。
+func (s *ConversationService) SetDisappearing(userID, conversationID string, seconds int64) (*store.ConversationSettings, error) {
+	if seconds < 0 || seconds > int64(global.MaxMessageTTL/time.Second) {
+		return nil, InvalidDisappearingError
+	}
+	if !canAccessConversation(s.hub, userID, conversationID) {
+		return nil, NotParticipantError
+	}
+
+	settings := s.conversations.SetDisappearing(conversationID, userID, seconds)
+
+	marshal, err := json.Marshal(settings)
+	if err != nil {
+		log.Printf("conversation settings json marshal error: %v", err)
+		return settings, nil
+	}
+	s.hub.Broadcast <- types.NewUpdateMessage(conversationID, types.NewMessageEventPayload(types.ConversationSettingsChanged, marshal))
+	return settings, nil
+}
+
+func lastMessageTime(conversation *dto.ConversationResponse) int64 {
+	if conversation.LastMessage == nil {
+		return 0
+	}
+	return conversation.LastMessage.Timestamp
+}
+
+// previewMessage 生成会话列表中展示的消息预览。
+func previewMessage(msg *types.Message) *dto.MessagePreview {
+	preview := &dto.MessagePreview{
+		ID:        msg.ID,
+		From:      msg.From,
+		Timestamp: msg.Timestamp,
+	}
+	if msg.Payload == nil {
+		return preview
+	}
+
+	preview.Type = msg.Payload.Type
+	switch msg.Payload.Type {
+	case types.PayloadTypeText, types.PayloadTypeMarkdown:
+		var text string
+		if err := json.Unmarshal(msg.Payload.Content, &text); err == nil {
+			preview.Text = truncateRunes(text, previewMaxRunes)
+		}
+	case types.PayloadTypeImage:
+		preview.Text = "[图片]"
+	case types.PayloadTypeFile:
+		preview.Text = "[文件]"
+	case types.PayloadTypePoll:
+		var poll types.Poll
+		if err := json.Unmarshal(msg.Payload.Content, &poll); err == nil {
+			preview.Text = truncateRunes("[投票] "+poll.Question, previewMaxRunes)
+		}
+	}
+	return preview
+}
+
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+// closeCodeDeviceRevoked 是设备被移除时发送的 websocket 关闭码，客户端收到后不应自动重连。
+const closeCodeDeviceRevoked = 4001
+
+var (
+	InvalidDeviceError    = errors.New("invalid device")
+	InvalidSessionError   = errors.New("invalid session")
+	DeviceRevokedError    = errors.New("device revoked")
+	DeviceNotFindError    = errors.New("device not find")
+	SessionForbiddenError = errors.New("session forbidden")
+)
+
+type DeviceService struct {
+	hub     *types.Hub
+	devices *store.DeviceStore
+}
+
+func NewDeviceService(hub *types.Hub, devices *store.DeviceStore) *DeviceService {
+	return &DeviceService{
+		hub:     hub,
+		devices: devices,
+	}
+}
+
+// Issue 在登录时为设备签发会话凭证，建立连接和调用其他接口时都需要携带。
+func (s *DeviceService) Issue(userID, deviceID string) (string, error) {
+	if userID == "" || deviceID == "" {
+		return "", InvalidDeviceError
+	}
+
+	token := utils.GenerateUUID()
+	s.devices.Issue(userID, deviceID, token)
+	return token, nil
+}
+
+// Link 由已登录的设备为用户的另一个设备签发会话凭证，设备原有的会话会失效。
+func (s *DeviceService) Link(userID, deviceID string) (string, error) {
+	return s.Issue(userID, deviceID)
+}
+
+// Authenticate 校验设备建立连接时携带的会话凭证。
+func (s *DeviceService) Authenticate(userID, deviceID, token string) error {
+	session, ok := s.devices.Get(userID, deviceID)
+	if !ok || token == "" || subtle.ConstantTimeCompare([]byte(session.Token), []byte(token)) != 1 {
+		return InvalidSessionError
+	}
+	if session.RevokeTime != 0 {
+		return DeviceRevokedError
+	}
+	return nil
+}
+
+// Authorize 校验 REST 请求携带的会话凭证，返回凭证所属的用户ID和设备ID。
+// 被移除的设备的凭证不能再使用。
+func (s *DeviceService) Authorize(token string) (string, string, error) {
+	if token == "" {
+		return "", "", SessionForbiddenError
+	}
+	userID, session, ok := s.devices.Lookup(token)
+	if !ok || session.RevokeTime != 0 {
+		return "", "", SessionForbiddenError
+	}
+	return userID, session.DeviceID, nil
+}
+
+// List 返回用户的在线设备，最近连接的在前。
+func (s *DeviceService) List(userID string) []*types.DeviceInfo {
+	devices := s.hub.UserDevices(userID)
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ConnectTime > devices[j].ConnectTime
+	})
+	return devices
+}
+
+// Revoke 移除设备：使设备的会话失效并关闭设备的连接。返回关闭的连接数。
+func (s *DeviceService) Revoke(userID, deviceID string) (int, error) {
+	if deviceID == "" {
+		return 0, InvalidDeviceError
+	}
+	if !s.devices.Revoke(userID, deviceID) {
+		return 0, DeviceNotFindError
+	}
+	closed := s.hub.CloseDevice(userID, deviceID, closeCodeDeviceRevoked, "device revoked")
+
+	marshal, _ := json.Marshal(deviceID)
+	s.hub.Broadcast <- types.NewSystemMessage(userID, types.NewMessageEventPayload(types.DeviceRevoked, marshal))
+	return closed, nil
+}
+
+// Restore 撤销对设备的移除，设备可以继续使用原来的会话建立连接。
+// 被移除的设备不能恢复自己，必须由用户的其他设备操作。
+func (s *DeviceService) Restore(userID, deviceID string) error {
+	if deviceID == "" {
+		return InvalidDeviceError
+	}
+	if !s.devices.Restore(userID, deviceID) {
+		return DeviceNotFindError
+	}
+	return nil
+}
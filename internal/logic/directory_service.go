@@ -0,0 +1,152 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+const (
+	directoryDefaultPageSize = 50
+	directoryMaxPageSize     = 200
+)
+
+var (
+	UserNotFindError = errors.New("user not find")
+)
+
+// DirectoryService 维护所有连接过的用户，离线用户也能被列出和查询。
+// 它作为 Hub 的 ConnectionListener，在最后一个设备断开时记录最后在线时间。
+type DirectoryService struct {
+	hub             *types.Hub
+	users           *store.UserStore
+	profiles        *store.ProfileStore
+	presenceService *PresenceService
+}
+
+func NewDirectoryService(hub *types.Hub, users *store.UserStore, profiles *store.ProfileStore, presenceService *PresenceService) *DirectoryService {
+	return &DirectoryService{
+		hub:             hub,
+		users:           users,
+		profiles:        profiles,
+		presenceService: presenceService,
+	}
+}
+
+func (s *DirectoryService) ClientConnected(client *types.Client) {
+	s.users.Touch(client.UserId, client.UserName, time.Now().Unix())
+}
+
+// ClientDisconnected 在用户没有在线设备时记录最后在线时间，隐身的用户不更新，避免暴露其曾经在线。
+func (s *DirectoryService) ClientDisconnected(client *types.Client) {
+	if _, online := s.hub.UserActivity(client.UserId); online {
+		return
+	}
+	if s.presenceService.Settings(client.UserId).State == types.PresenceInvisible {
+		return
+	}
+	s.users.SetLastSeen(client.UserId, time.Now().Unix())
+}
+
+// Name 返回用户名称，优先使用用户设置的显示名称，离线用户返回最后一次连接时的名称。
+func (s *DirectoryService) Name(userID string) string {
+	if profile := s.profiles.Get(userID); profile.DisplayName != "" {
+		return profile.DisplayName
+	}
+	if user, ok := s.users.Get(userID); ok {
+		return user.Name
+	}
+	name, _ := s.hub.UserName(userID)
+	return name
+}
+
+// Online 返回所有在线且没有隐身的用户。
+func (s *DirectoryService) Online() []*dto.UserVO {
+	result := make([]*dto.UserVO, 0)
+	for _, userID := range s.hub.OnlineUserIDs() {
+		user, ok := s.users.Get(userID)
+		if !ok {
+			// 目录在连接通知中异步更新，刚连接的用户可能还没有记录
+			name, _ := s.hub.UserName(userID)
+			user = &store.UserRecord{ID: userID, Name: name}
+		}
+		vo := s.userVO(user)
+		if vo.Presence.Visible() {
+			result = append(result, vo)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// List 分页返回目录中的所有用户和用户总数，在线的排在前面。limit 为 0 时使用默认分页大小。
+func (s *DirectoryService) List(offset, limit int) ([]*dto.UserVO, int) {
+	if limit <= 0 {
+		limit = directoryDefaultPageSize
+	}
+	limit = min(limit, directoryMaxPageSize)
+	offset = max(offset, 0)
+
+	users := s.users.List()
+	result := make([]*dto.UserVO, 0, len(users))
+	for _, user := range users {
+		result = append(result, s.userVO(user))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Presence.Visible() != result[j].Presence.Visible() {
+			return result[i].Presence.Visible()
+		}
+		if result[i].LastSeen != result[j].LastSeen {
+			return result[i].LastSeen > result[j].LastSeen
+		}
+		return result[i].ID < result[j].ID
+	})
+	total := len(result)
+	if offset >= total {
+		return []*dto.UserVO{}, total
+	}
+	return result[offset:min(offset+limit, total)], total
+}
+
+// Get 返回单个用户。
+func (s *DirectoryService) Get(userID string) (*dto.UserVO, error) {
+	user, ok := s.users.Get(userID)
+	if !ok {
+		return nil, UserNotFindError
+	}
+	return s.userVO(user), nil
+}
+
+func (s *DirectoryService) userVO(user *store.UserRecord) *dto.UserVO {
+	profile := s.profiles.Get(user.ID)
+	vo := &dto.UserVO{
+		ID:          user.ID,
+		Name:        user.Name,
+		DisplayName: profile.DisplayName,
+		Bio:         profile.Bio,
+		Timezone:    profile.Timezone,
+		Presence:    s.presenceService.Presence(user.ID),
+		FirstSeen:   user.FirstSeen,
+	}
+	if vo.DisplayName == "" {
+		vo.DisplayName = user.Name
+	}
+	if profile.Avatar != nil {
+		// 带上修改时间，头像更换后客户端不会使用缓存的旧头像
+		vo.Avatar = fmt.Sprintf("/v1/api/users/%s/avatar?v=%d", user.ID, profile.UpdateTime)
+	}
+	// 在线用户不需要最后在线时间
+	if !vo.Presence.Visible() {
+		vo.LastSeen = user.LastSeen
+	}
+	return vo
+}
@@ -0,0 +1,275 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/media"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	FileNotFindError     = errors.New("file not find")
+	FileTooLargeError    = errors.New("file too large")
+	InvalidFileTypeError = errors.New("invalid file conversation type")
+	InvalidImageError    = errors.New("invalid image")
+)
+
+type FileService struct {
+	hub   *types.Hub
+	blobs store.BlobStore
+	files *store.FileStore
+}
+
+func NewFileService(hub *types.Hub, blobs store.BlobStore, files *store.FileStore) *FileService {
+	return &FileService{
+		hub:   hub,
+		blobs: blobs,
+		files: files,
+	}
+}
+
+// Upload 保存上传的文件，文件归属于 uploaderID 与 to 之间的会话。
+// messageType 为 MessageTypeRoom 时 to 是房间ID，为 MessageTypeUser 时 to 是对方用户ID，
+// 为 MessageTypeGroup 时 to 是群组ID。
+func (s *FileService) Upload(uploaderID string, messageType types.MessageType, to string, header *multipart.FileHeader) (*store.FileRecord, error) {
+	if header.Size > global.MaxUploadSize {
+		return nil, FileTooLargeError
+	}
+
+	conversationID, err := s.uploadConversation(uploaderID, messageType, to)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return s.save(uploaderID, conversationID, header.Filename, file, global.MaxUploadSize)
+}
+
+// uploadConversation 返回上传目标会话，并校验上传者是会话成员。
+func (s *FileService) uploadConversation(uploaderID string, messageType types.MessageType, to string) (string, error) {
+	conversationID := types.ConversationIDOf(&types.Message{Type: messageType, From: uploaderID, To: to})
+	if conversationID == "" {
+		return "", InvalidFileTypeError
+	}
+	if !canAccessConversation(s.hub, uploaderID, conversationID) {
+		return "", NotParticipantError
+	}
+	return conversationID, nil
+}
+
+// save 把文件内容写入存储并生成记录，文件超过 limit 字节时返回 FileTooLargeError。
+// 图片会先去除 EXIF 等元数据，再生成缩略图一并保存，图片需要整体读入内存，
+// 因此不能超过 MaxUploadSize。
+func (s *FileService) save(uploaderID, conversationID, name string, file io.Reader, limit int64) (*store.FileRecord, error) {
+	// 根据文件头部内容判断类型，再把读出的部分拼回去写入存储
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	record := &store.FileRecord{
+		ID:             utils.GenerateUUID(),
+		Name:           name,
+		MIME:           http.DetectContentType(head),
+		UploaderID:     uploaderID,
+		ConversationID: conversationID,
+		CreateTime:     time.Now(),
+	}
+
+	// 超过 limit 时读取返回 FileTooLargeError，不能把截断后的文件当作成功保存
+	var reader io.Reader = &sizeLimitReader{reader: io.MultiReader(bytes.NewReader(head), file), remaining: limit}
+	if media.IsImageMIME(record.MIME) {
+		data, err := io.ReadAll(io.LimitReader(reader, global.MaxUploadSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > global.MaxUploadSize {
+			return nil, FileTooLargeError
+		}
+		if data, err = s.saveImage(record, data); err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	record.BlobKey, record.Size, err = s.blobs.Put(reader)
+	if err != nil {
+		if record.Thumbnail != nil {
+			s.deleteBlob(record.Thumbnail.BlobKey)
+		}
+		return nil, err
+	}
+
+	s.files.Save(record)
+	return record, nil
+}
+
+// sizeLimitReader 在读取的字节数超过 remaining 时返回 FileTooLargeError。
+type sizeLimitReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, FileTooLargeError
+	}
+	return n, err
+}
+
+// saveImage 去除图片元数据、记录尺寸并保存缩略图，返回去除元数据后的图片内容。
+func (s *FileService) saveImage(record *store.FileRecord, data []byte) ([]byte, error) {
+	data, err := media.StripMetadata(data, record.MIME)
+	if err != nil {
+		return nil, InvalidImageError
+	}
+
+	info, err := media.ProcessImage(data, record.MIME)
+	if err != nil {
+		return nil, InvalidImageError
+	}
+
+	thumbKey, _, err := s.blobs.Put(bytes.NewReader(info.Thumbnail))
+	if err != nil {
+		return nil, err
+	}
+
+	record.Width = info.Width
+	record.Height = info.Height
+	record.Thumbnail = &store.Thumbnail{
+		BlobKey: thumbKey,
+		MIME:    info.ThumbMIME,
+		Size:    int64(len(info.Thumbnail)),
+		Width:   info.ThumbW,
+		Height:  info.ThumbH,
+	}
+	return data, nil
+}
+
+// Open 校验用户可以访问文件所在的会话后打开文件内容。
+func (s *FileService) Open(userID, fileID string) (*store.FileRecord, io.ReadCloser, error) {
+	record, ok := s.files.Get(fileID)
+	if !ok {
+		return nil, nil, FileNotFindError
+	}
+	if !canAccessConversation(s.hub, userID, record.ConversationID) {
+		return nil, nil, NotParticipantError
+	}
+
+	reader, err := s.blobs.Open(record.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, reader, nil
+}
+
+// CopyToConversation 为另一个会话创建引用同一份数据的文件记录，用于转发。
+func (s *FileService) CopyToConversation(fileID, conversationID, userID string) (string, error) {
+	record, ok := s.files.Get(fileID)
+	if !ok {
+		return "", FileNotFindError
+	}
+
+	// 副本与原记录共享数据，各自持有一个引用
+	s.blobs.Retain(record.BlobKey)
+	if record.Thumbnail != nil {
+		s.blobs.Retain(record.Thumbnail.BlobKey)
+	}
+
+	copied := *record
+	copied.ID = utils.GenerateUUID()
+	copied.ConversationID = conversationID
+	copied.UploaderID = userID
+	copied.CreateTime = time.Now()
+	s.files.Save(&copied)
+	return copied.ID, nil
+}
+
+// Delete 删除文件记录，并释放记录引用的数据和缩略图。
+func (s *FileService) Delete(fileID string) {
+	record, ok := s.files.Get(fileID)
+	if !ok {
+		return
+	}
+	s.files.Delete(fileID)
+
+	s.deleteBlob(record.BlobKey)
+	if record.Thumbnail != nil {
+		s.deleteBlob(record.Thumbnail.BlobKey)
+	}
+}
+
+func (s *FileService) deleteBlob(key string) {
+	if err := s.blobs.Delete(key); err != nil {
+		log.Printf("delete blob error: %s, %v", key, err)
+	}
+}
+
+// OpenThumbnail 校验用户可以访问文件所在的会话后打开图片缩略图。
+func (s *FileService) OpenThumbnail(userID, fileID string) (*store.Thumbnail, io.ReadCloser, error) {
+	record, ok := s.files.Get(fileID)
+	if !ok || record.Thumbnail == nil {
+		return nil, nil, FileNotFindError
+	}
+	if !canAccessConversation(s.hub, userID, record.ConversationID) {
+		return nil, nil, NotParticipantError
+	}
+
+	reader, err := s.blobs.Open(record.Thumbnail.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record.Thumbnail, reader, nil
+}
+
+// AttachFile 校验消息引用的文件属于消息所在的会话，并把文件元数据附加到消息上，
+// 客户端不需要下载原文件就可以渲染预览。
+func (s *FileService) AttachFile(conversationID string, payload *types.Payload) error {
+	if payload == nil {
+		return nil
+	}
+	payload.FileMeta = nil
+	if payload.FileID == "" {
+		return nil
+	}
+	record, ok := s.files.Get(payload.FileID)
+	if !ok {
+		return FileNotFindError
+	}
+	if record.ConversationID != conversationID {
+		return NotParticipantError
+	}
+
+	payload.FileMeta = &types.FileMeta{
+		Name:   record.Name,
+		Size:   record.Size,
+		MIME:   record.MIME,
+		Width:  record.Width,
+		Height: record.Height,
+	}
+	if record.Thumbnail != nil {
+		payload.FileMeta.ThumbnailURL = fmt.Sprintf("/v1/api/files/%s/thumbnail", record.ID)
+		payload.FileMeta.ThumbnailWidth = record.Thumbnail.Width
+		payload.FileMeta.ThumbnailHeight = record.Thumbnail.Height
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/entity"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+const (
+	groupMaxMembers   = 100
+	groupMaxNameRunes = 50
+)
+
+var (
+	InvalidGroupError   = errors.New("invalid group")
+	GroupTooLargeError  = types.GroupTooLargeError
+	GroupNotFindError   = types.GroupNotFindError
+	NotGroupOwnerError  = types.NotGroupOwnerError
+	NotGroupMemberError = types.NotGroupMemberError
+)
+
+// GroupService 管理私有群组。群组不会出现在房间列表中，只有成员可以看到和发送消息。
+type GroupService struct {
+	hub              *types.Hub
+	directoryService *DirectoryService
+	privacyService   *PrivacyService
+}
+
+func NewGroupService(hub *types.Hub, directoryService *DirectoryService, privacyService *PrivacyService) *GroupService {
+	return &GroupService{
+		hub:              hub,
+		directoryService: directoryService,
+		privacyService:   privacyService,
+	}
+}
+
+// Create 由 ownerID 创建群组，members 为除创建者外的初始成员。
+func (s *GroupService) Create(ownerID, name string, members []string) (*dto.GroupResponse, error) {
+	name = strings.TrimSpace(name)
+	members = normalizeMembers(members)
+	if ownerID == "" || len([]rune(name)) > groupMaxNameRunes || len(members) == 0 {
+		return nil, InvalidGroupError
+	}
+	if len(members)+1 > groupMaxMembers {
+		return nil, GroupTooLargeError
+	}
+	if err := s.checkInvite(ownerID, members, nil); err != nil {
+		return nil, err
+	}
+
+	group := s.hub.CreateGroup(types.NewGroup(utils.GenerateUUID(), name, ownerID, members))
+	s.notifyUpdated(group)
+	return s.response(group), nil
+}
+
+// List 返回用户所在的群组。
+func (s *GroupService) List(userID string) []*dto.GroupResponse {
+	groups := s.hub.UserGroups(userID)
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].CreateTime.After(groups[j].CreateTime)
+	})
+
+	result := make([]*dto.GroupResponse, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, s.response(group))
+	}
+	return result
+}
+
+// Detail 返回群组详情，只有成员可以查看。
+func (s *GroupService) Detail(userID, groupID string) (*dto.GroupResponse, error) {
+	group, ok := s.hub.Group(groupID)
+	if !ok {
+		return nil, GroupNotFindError
+	}
+	if !group.Members[userID] {
+		return nil, NotGroupMemberError
+	}
+	return s.response(group), nil
+}
+
+// AddMembers 由群组成员添加新成员。
+func (s *GroupService) AddMembers(userID, groupID string, members []string) (*dto.GroupResponse, error) {
+	members = normalizeMembers(members)
+	if len(members) == 0 {
+		return nil, InvalidGroupError
+	}
+	current, ok := s.hub.Group(groupID)
+	if !ok {
+		return nil, GroupNotFindError
+	}
+	if err := s.checkInvite(userID, members, current); err != nil {
+		return nil, err
+	}
+	group, err := s.hub.AddGroupMembers(groupID, userID, members, groupMaxMembers)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyUpdated(group)
+	return s.response(group), nil
+}
+
+// RemoveMember 移除群组成员，成员移除自己即退出群组。
+func (s *GroupService) RemoveMember(userID, groupID, memberID string) error {
+	group, err := s.hub.RemoveGroupMember(groupID, userID, memberID)
+	if err != nil {
+		return err
+	}
+
+	// 被移除的成员已经收不到群组会话的消息，单独通知
+	marshal, _ := json.Marshal(group.ID)
+	s.hub.Broadcast <- types.NewSystemMessage(memberID, types.NewMessageEventPayload(types.GroupRemoved, marshal))
+	if len(group.Members) > 0 {
+		s.notifyUpdated(group)
+	}
+	return nil
+}
+
+// checkInvite 校验 actorID 可以把 members 拉入群组：拉人入群后就可以给对方发群消息，
+// 因此与私聊一样受屏蔽列表和隐私设置限制。已经在群组中的成员不再检查。
+func (s *GroupService) checkInvite(actorID string, members []string, group *types.Group) error {
+	for _, member := range members {
+		if group != nil && group.Members[member] {
+			continue
+		}
+		if err := s.privacyService.CheckDM(actorID, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyUpdated 通知所有成员群组信息发生变化。
+func (s *GroupService) notifyUpdated(group *types.Group) {
+	marshal, err := json.Marshal(s.response(group))
+	if err != nil {
+		log.Printf("group json marshal error: %v", err)
+		return
+	}
+	s.hub.Broadcast <- types.NewUpdateMessage(types.GroupConversationID(group.ID), types.NewMessageEventPayload(types.GroupUpdated, marshal))
+}
+
+func (s *GroupService) response(group *types.Group) *dto.GroupResponse {
+	memberIDs := group.MemberIDs()
+	sort.Strings(memberIDs)
+
+	members := make([]*dto.UserVO, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		members = append(members, &dto.UserVO{ID: memberID, Name: s.directoryService.Name(memberID)})
+	}
+	return &dto.GroupResponse{
+		ID:         group.ID,
+		Name:       group.Name,
+		OwnerID:    group.OwnerID,
+		Count:      len(members),
+		CreateTime: entity.BizTimeFull(group.CreateTime),
+		Members:    members,
+	}
+}
+
+// normalizeMembers 去除空ID和重复ID。
+func normalizeMembers(members []string) []string {
+	seen := make(map[string]bool, len(members))
+	result := make([]string, 0, len(members))
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" || seen[member] {
+			continue
+		}
+		seen[member] = true
+		result = append(result, member)
+	}
+	return result
+}
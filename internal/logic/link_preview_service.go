@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/linkpreview"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// LinkPreviewService 为文本消息中的链接生成预览卡片。
+// 抓取在后台进行，完成后写回消息并以 MessageUpdated 事件通知会话参与者。
+type LinkPreviewService struct {
+	hub      *types.Hub
+	messages *store.MessageStore
+	fetcher  linkpreview.Fetcher
+}
+
+func NewLinkPreviewService(hub *types.Hub, messages *store.MessageStore, fetcher linkpreview.Fetcher) *LinkPreviewService {
+	return &LinkPreviewService{
+		hub:      hub,
+		messages: messages,
+		fetcher:  fetcher,
+	}
+}
+
+// Unfurl 提取消息中的链接并在后台抓取预览，不阻塞消息发送。
+func (s *LinkPreviewService) Unfurl(message *types.Message) {
+	urls := extractURLs(message.Payload)
+	if len(urls) == 0 {
+		return
+	}
+
+	go func() {
+		previews := make([]*types.LinkPreview, 0, len(urls))
+		for _, rawURL := range urls {
+			ctx, cancel := context.WithTimeout(context.Background(), global.LinkPreviewTimeout)
+			preview, err := s.fetcher.Fetch(ctx, rawURL)
+			cancel()
+			if err != nil {
+				log.Printf("link preview error: %s, %v", rawURL, err)
+				continue
+			}
+			if preview.Title == "" && preview.Description == "" {
+				continue
+			}
+			previews = append(previews, preview)
+		}
+		if len(previews) == 0 {
+			return
+		}
+
+		updated, ok := s.messages.SetLinkPreviews(message.ID, previews)
+		if !ok {
+			// 消息可能已经过期被删除
+			return
+		}
+		s.notifyUpdated(updated)
+	}()
+}
+
+func (s *LinkPreviewService) notifyUpdated(message *types.Message) {
+	conversationID := types.ConversationIDOf(message)
+	marshal, err := json.Marshal(map[string]any{
+		"messageId":      message.ID,
+		"conversationId": conversationID,
+		"linkPreviews":   message.Payload.LinkPreviews,
+	})
+	if err != nil {
+		log.Printf("message updated json marshal error: %v", err)
+		return
+	}
+	s.hub.Broadcast <- types.NewUpdateMessage(conversationID, types.NewMessageEventPayload(types.MessageUpdated, marshal))
+}
+
+// extractURLs 返回文本或 Markdown 消息中去重后的链接，最多 MaxLinkPreviews 个。
+func extractURLs(payload *types.Payload) []string {
+	if payload == nil || (payload.Type != types.PayloadTypeText && payload.Type != types.PayloadTypeMarkdown) {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(payload.Content, &text); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?，。；：！？")
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		urls = append(urls, match)
+		if len(urls) == global.MaxLinkPreviews {
+			break
+		}
+	}
+	return urls
+}
@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+// MessageSweeper 定期删除已过期的阅后即焚消息及其附带的文件和投票，并通知在线的会话参与者。
+type MessageSweeper struct {
+	hub         *types.Hub
+	messages    *store.MessageStore
+	fileService *FileService
+	pollService *PollService
+}
+
+func NewMessageSweeper(hub *types.Hub, messages *store.MessageStore, fileService *FileService, pollService *PollService) *MessageSweeper {
+	return &MessageSweeper{
+		hub:         hub,
+		messages:    messages,
+		fileService: fileService,
+		pollService: pollService,
+	}
+}
+
+func (s *MessageSweeper) Run() {
+	ticker := time.NewTicker(global.ExpirySweepPeriod)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.sweep(now.Unix())
+	}
+}
+
+// sweep 删除过期时间不晚于 now 的消息。
+func (s *MessageSweeper) sweep(now int64) {
+	for _, msg := range s.messages.DeleteExpired(now) {
+		s.deleteAttachments(msg)
+		s.notifyDeleted(msg)
+	}
+}
+
+// deleteAttachments 删除消息附带的内容，否则过期后仍然可以通过文件接口下载。
+// 同一个文件可能被会话中的其他消息引用，此时保留文件。
+func (s *MessageSweeper) deleteAttachments(msg *types.Message) {
+	if msg.Payload == nil {
+		return
+	}
+	if msg.Payload.FileID != "" && !s.messages.HasFile(types.ConversationIDOf(msg), msg.Payload.FileID) {
+		s.fileService.Delete(msg.Payload.FileID)
+	}
+	if msg.Payload.Type == types.PayloadTypePoll {
+		s.pollService.Delete(msg.ID)
+	}
+}
+
+func (s *MessageSweeper) notifyDeleted(msg *types.Message) {
+	conversationID := types.ConversationIDOf(msg)
+	marshal, err := json.Marshal(map[string]string{
+		"messageId":      msg.ID,
+		"conversationId": conversationID,
+	})
+	if err != nil {
+		log.Printf("message deleted json marshal error: %v", err)
+		return
+	}
+
+	s.hub.Broadcast <- types.NewUpdateMessage(conversationID, types.NewMessageEventPayload(types.MessageDeleted, marshal))
+}
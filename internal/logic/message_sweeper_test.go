@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+// sweepMessage 是 alice 发给 bob 的一条消息，file 为引用的文件名，为空表示没有文件。
+type sweepMessage struct {
+	id       string
+	expireAt int64
+	file     string
+	poll     bool
+}
+
+func TestMessageSweep(t *testing.T) {
+	const now = 100
+
+	cases := []struct {
+		name        string
+		messages    []sweepMessage
+		wantKept    []string        // 清理后仍然存在的消息
+		wantFiles   map[string]bool // 文件是否仍然存在
+		wantPolls   map[string]bool // 投票是否仍然存在
+		wantDeleted int             // bob 收到的 MessageDeleted 通知数
+	}{
+		{
+			name:        "expired message and its file",
+			messages:    []sweepMessage{{id: "m1", expireAt: now, file: "a"}},
+			wantFiles:   map[string]bool{"a": false},
+			wantDeleted: 1,
+		},
+		{
+			name:      "message not yet expired",
+			messages:  []sweepMessage{{id: "m1", expireAt: now + 1, file: "a"}},
+			wantKept:  []string{"m1"},
+			wantFiles: map[string]bool{"a": true},
+		},
+		{
+			name:     "message without ttl",
+			messages: []sweepMessage{{id: "m1", file: "a"}},
+			wantKept: []string{"m1"},
+		},
+		{
+			name: "file still referenced by a live message",
+			messages: []sweepMessage{
+				{id: "m1", expireAt: now, file: "a"},
+				{id: "m2", file: "a"},
+			},
+			wantKept:    []string{"m2"},
+			wantFiles:   map[string]bool{"a": true},
+			wantDeleted: 1,
+		},
+		{
+			name: "file referenced only by expired messages",
+			messages: []sweepMessage{
+				{id: "m1", expireAt: now - 10, file: "a"},
+				{id: "m2", expireAt: now, file: "a"},
+				{id: "m3", expireAt: now, file: "b"},
+			},
+			wantFiles:   map[string]bool{"a": false, "b": false},
+			wantDeleted: 3,
+		},
+		{
+			name: "expired poll",
+			messages: []sweepMessage{
+				{id: "m1", expireAt: now, poll: true},
+				{id: "m2", expireAt: now + 1, poll: true},
+			},
+			wantKept:    []string{"m2"},
+			wantPolls:   map[string]bool{"m1": false, "m2": true},
+			wantDeleted: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := newTestHub()
+			messages := store.NewMessageStore()
+			blobs, err := store.NewLocalBlobStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("blob store: %v", err)
+			}
+			fileService := NewFileService(hub, blobs, store.NewFileStore())
+			pollService := NewPollService(hub, store.NewPollStore())
+			sweeper := NewMessageSweeper(hub, messages, fileService, pollService)
+
+			newTestConn(t, hub, "alice", "a1")
+			bob := newTestConn(t, hub, "bob", "b1")
+
+			conversationID := types.DirectConversationID("alice", "bob")
+			files := make(map[string]*store.FileRecord)
+			for _, m := range tc.messages {
+				payload := types.NewPayload(types.PayloadTypeText, []byte(`"hi"`))
+				if m.file != "" {
+					if files[m.file] == nil {
+						record, err := fileService.save("alice", conversationID, m.file+".txt", strings.NewReader("content of "+m.file), 1<<20)
+						if err != nil {
+							t.Fatalf("save file: %v", err)
+						}
+						files[m.file] = record
+					}
+					payload = types.NewPayload(types.PayloadTypeFile, nil)
+					payload.FileID = files[m.file].ID
+				}
+
+				message := types.NewMessage(types.MessageTypeUser, payload, "alice", "bob")
+				message.ID = m.id
+				message.ExpireAt = m.expireAt
+				if m.poll {
+					message.Payload = types.NewPayload(types.PayloadTypePoll, []byte(`{"question":"lunch?","options":[{"text":"yes"},{"text":"no"}]}`))
+					if err := pollService.Create(conversationID, message); err != nil {
+						t.Fatalf("create poll: %v", err)
+					}
+				}
+				messages.Save(conversationID, message)
+			}
+
+			sweeper.sweep(now)
+
+			var kept []string
+			for _, m := range messages.Range(conversationID, 0, int64(len(tc.messages))) {
+				kept = append(kept, m.ID)
+			}
+			sort.Strings(kept)
+			if strings.Join(kept, ",") != strings.Join(tc.wantKept, ",") {
+				t.Errorf("kept = %v, want %v", kept, tc.wantKept)
+			}
+
+			for name, want := range tc.wantFiles {
+				_, reader, err := fileService.Open("alice", files[name].ID)
+				if err == nil {
+					_ = reader.Close()
+				}
+				if exists := err == nil; exists != want {
+					t.Errorf("file %s exists = %v (%v), want %v", name, exists, err, want)
+				}
+				// 记录删除后数据也要释放，否则仍然占用存储
+				blob, err := blobs.Open(files[name].BlobKey)
+				if err == nil {
+					_ = blob.Close()
+				}
+				if exists := err == nil; exists != want {
+					t.Errorf("blob of %s exists = %v, want %v", name, exists, want)
+				}
+			}
+
+			for pollID, want := range tc.wantPolls {
+				_, err := pollService.Result("alice", pollID)
+				if exists := !errors.Is(err, PollNotFindError); exists != want {
+					t.Errorf("poll %s exists = %v (%v), want %v", pollID, exists, err, want)
+				}
+			}
+
+			if deleted := bob.events(t, types.MessageDeleted); len(deleted) != tc.wantDeleted {
+				t.Errorf("deleted notifications = %d, want %d", len(deleted), tc.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+const (
+	pollMaxOptions       = 20
+	pollMaxQuestionRunes = 200
+	pollMaxOptionRunes   = 100
+)
+
+var (
+	InvalidPollError = errors.New("invalid poll")
+	PollNotFindError = errors.New("poll not find")
+	PollClosedError  = errors.New("poll closed")
+	InvalidVoteError = errors.New("invalid vote")
+)
+
+// PollService 负责投票的创建和计票，票数只在服务端统计，客户端无法伪造结果。
+type PollService struct {
+	hub   *types.Hub
+	polls *store.PollStore
+}
+
+func NewPollService(hub *types.Hub, polls *store.PollStore) *PollService {
+	return &PollService{
+		hub:   hub,
+		polls: polls,
+	}
+}
+
+// Create 校验投票消息的内容，分配选项ID并以规范化后的内容替换消息内容。
+// 投票ID与消息ID相同，调用前消息需要已经分配ID。
+func (s *PollService) Create(conversationID string, message *types.Message) error {
+	poll, err := parsePoll(message.Payload.Content)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(poll)
+	if err != nil {
+		return err
+	}
+	message.Payload.Content = content
+	message.Payload.File = nil
+
+	s.polls.Save(&store.PollRecord{
+		ID:             message.ID,
+		ConversationID: conversationID,
+		CreatorID:      message.From,
+		Poll:           poll,
+		Votes:          make(map[string][]string),
+	})
+	return nil
+}
+
+// Validate 只校验投票消息的内容，不创建投票。
+func (s *PollService) Validate(payload *types.Payload) error {
+	_, err := parsePoll(payload.Content)
+	return err
+}
+
+// parsePoll 校验投票内容并分配选项ID。
+func parsePoll(content json.RawMessage) (*types.Poll, error) {
+	var poll types.Poll
+	if err := json.Unmarshal(content, &poll); err != nil {
+		return nil, InvalidPollError
+	}
+
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" || len([]rune(poll.Question)) > pollMaxQuestionRunes {
+		return nil, InvalidPollError
+	}
+	if len(poll.Options) < 2 || len(poll.Options) > pollMaxOptions {
+		return nil, InvalidPollError
+	}
+	if poll.CloseAt != 0 && poll.CloseAt <= time.Now().Unix() {
+		return nil, InvalidPollError
+	}
+
+	options := make([]*types.PollOption, 0, len(poll.Options))
+	for i, option := range poll.Options {
+		if option == nil {
+			return nil, InvalidPollError
+		}
+		text := strings.TrimSpace(option.Text)
+		if text == "" || len([]rune(text)) > pollMaxOptionRunes {
+			return nil, InvalidPollError
+		}
+		options = append(options, &types.PollOption{ID: strconv.Itoa(i + 1), Text: text})
+	}
+	poll.Options = options
+	return &poll, nil
+}
+
+// Vote 记录投票并向会话成员推送最新票数。
+func (s *PollService) Vote(client *types.Client, vote *types.Vote) error {
+	if vote == nil {
+		return InvalidVoteError
+	}
+
+	record, ok := s.polls.Get(vote.PollID)
+	if !ok {
+		return PollNotFindError
+	}
+	if !canAccessConversation(s.hub, client.UserId, record.ConversationID) {
+		return NotParticipantError
+	}
+	if pollClosed(record.Poll) {
+		return PollClosedError
+	}
+
+	optionIDs, err := checkVoteOptions(record.Poll, vote.OptionIDs)
+	if err != nil {
+		return err
+	}
+
+	result, ok := s.polls.SetVote(record.ID, client.UserId, optionIDs)
+	if !ok {
+		return PollNotFindError
+	}
+
+	marshal, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("poll result json marshal error: %v", err)
+		return nil
+	}
+	s.hub.Broadcast <- types.NewUpdateMessage(record.ConversationID, types.NewMessageEventPayload(types.PollUpdated, marshal))
+	return nil
+}
+
+// Result 返回投票的当前结果，只有会话成员可以查看。
+func (s *PollService) Result(userID, pollID string) (*types.PollResult, error) {
+	record, ok := s.polls.Get(pollID)
+	if !ok {
+		return nil, PollNotFindError
+	}
+	if !canAccessConversation(s.hub, userID, record.ConversationID) {
+		return nil, NotParticipantError
+	}
+
+	result, ok := s.polls.Result(pollID, userID)
+	if !ok {
+		return nil, PollNotFindError
+	}
+	result.Closed = pollClosed(record.Poll)
+	return result, nil
+}
+
+// Delete 删除投票及其选票，用于投票消息被删除时。
+func (s *PollService) Delete(pollID string) {
+	s.polls.Delete(pollID)
+}
+
+func pollClosed(poll *types.Poll) bool {
+	return poll.CloseAt != 0 && poll.CloseAt <= time.Now().Unix()
+}
+
+// checkVoteOptions 校验选项存在且符合单选/多选的限制，返回去重后的选项。
+func checkVoteOptions(poll *types.Poll, optionIDs []string) ([]string, error) {
+	valid := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+
+	seen := make(map[string]bool, len(optionIDs))
+	result := make([]string, 0, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if !valid[optionID] {
+			return nil, InvalidVoteError
+		}
+		if !seen[optionID] {
+			seen[optionID] = true
+			result = append(result, optionID)
+		}
+	}
+	if !poll.Multiple && len(result) > 1 {
+		return nil, InvalidVoteError
+	}
+	return result, nil
+}
@@ -0,0 +1,332 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+const statusTextMaxRunes = 100
+
+var (
+	InvalidPresenceError       = errors.New("invalid presence")
+	TooManySubscriptionsError  = errors.New("too many presence subscriptions")
+	InvalidSubscriptionError   = errors.New("invalid presence subscription")
+	SubscriptionForbiddenError = errors.New("presence subscription not allowed")
+)
+
+// PresenceService 根据用户的连接、活动和自己的设置计算在线状态。
+// 状态变化在 PresenceCoalesceWindow 内合并后，只发给订阅了该用户的连接；
+// 从未订阅过的旧客户端仍然收到全局的 ReloadUsers 通知。
+// 它作为 Hub 的 ConnectionListener，负责发出上线、离线通知。
+type PresenceService struct {
+	hub       *types.Hub
+	presences *store.PresenceStore
+	isContact func(userID, otherID string) bool // 判断两个用户是否是联系人，为空时只允许订阅共同房间、群组的成员
+
+	// mu 保护下面的字段，并保证同一时刻只有一次状态计算，避免较早的计算结果覆盖较新的结果
+	mu            sync.Mutex
+	published     map[string]*types.Presence        // 用户ID -> 最近一次计算的可见状态
+	pending       map[string]*types.Presence        // 等待通知的状态变化
+	pendingReload []string                          // 等待通知旧客户端的上线、离线用户
+	subscriptions map[*types.Client]map[string]bool // 连接 -> 订阅的用户ID
+	subscribers   map[string]map[*types.Client]bool // 用户ID -> 订阅的连接
+}
+
+func NewPresenceService(hub *types.Hub, presences *store.PresenceStore) *PresenceService {
+	return &PresenceService{
+		hub:           hub,
+		presences:     presences,
+		published:     make(map[string]*types.Presence),
+		pending:       make(map[string]*types.Presence),
+		subscriptions: make(map[*types.Client]map[string]bool),
+		subscribers:   make(map[string]map[*types.Client]bool),
+	}
+}
+
+// SetContactFunc 设置联系人判断，联系人服务依赖在线状态服务，因此在创建后再设置。
+func (s *PresenceService) SetContactFunc(isContact func(userID, otherID string) bool) {
+	s.isContact = isContact
+}
+
+func (s *PresenceService) ClientConnected(client *types.Client) {
+	s.refresh(client.UserId)
+}
+
+func (s *PresenceService) ClientDisconnected(client *types.Client) {
+	s.mu.Lock()
+	for userID := range s.subscriptions[client] {
+		s.unsubscribeNoLock(client, userID)
+	}
+	delete(s.subscriptions, client)
+	s.mu.Unlock()
+
+	s.refresh(client.UserId)
+}
+
+// Run 定期检查空闲的用户和过期的自定义状态，并发出合并后的状态变化通知。
+func (s *PresenceService) Run() {
+	checkTicker := time.NewTicker(global.PresenceCheckPeriod)
+	defer checkTicker.Stop()
+	flushTicker := time.NewTicker(global.PresenceCoalesceWindow)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-checkTicker.C:
+			for _, userID := range s.presences.ClearExpiredStatus(time.Now().Unix()) {
+				s.refresh(userID)
+			}
+			for _, userID := range s.hub.OnlineUserIDs() {
+				s.refresh(userID)
+			}
+		case <-flushTicker.C:
+			s.flush()
+		}
+	}
+}
+
+// Subscribe 修改连接订阅的用户，并立即把新订阅用户的当前状态发给该连接。
+func (s *PresenceService) Subscribe(client *types.Client, subscription *types.Subscription) error {
+	if subscription == nil {
+		return InvalidSubscriptionError
+	}
+	// 只能订阅联系人和有共同房间、群组的用户，避免任何人都能追踪任意用户的在线状态
+	for _, userID := range subscription.Add {
+		if userID != "" && !s.canSubscribe(client.UserId, userID) {
+			return SubscriptionForbiddenError
+		}
+	}
+
+	s.mu.Lock()
+	subscribed := s.subscriptions[client]
+
+	// 先计算本次修改后的订阅数，超过上限时不做任何修改
+	removed := make(map[string]bool, len(subscription.Remove))
+	for _, userID := range subscription.Remove {
+		if subscribed[userID] {
+			removed[userID] = true
+		}
+	}
+	var added []string
+	adding := make(map[string]bool, len(subscription.Add))
+	for _, userID := range subscription.Add {
+		if userID == "" || adding[userID] || (subscribed[userID] && !removed[userID]) {
+			continue
+		}
+		adding[userID] = true
+		added = append(added, userID)
+	}
+	if len(subscribed)-len(removed)+len(added) > global.MaxPresenceSubscriptions {
+		s.mu.Unlock()
+		return TooManySubscriptionsError
+	}
+
+	if subscribed == nil {
+		subscribed = make(map[string]bool)
+		s.subscriptions[client] = subscribed
+	}
+	for userID := range removed {
+		s.unsubscribeNoLock(client, userID)
+	}
+	for _, userID := range added {
+		subscribed[userID] = true
+		if _, ok := s.subscribers[userID]; !ok {
+			s.subscribers[userID] = make(map[*types.Client]bool)
+		}
+		s.subscribers[userID][client] = true
+	}
+	s.mu.Unlock()
+
+	if len(added) == 0 {
+		return nil
+	}
+	snapshot := make([]*types.Presence, 0, len(added))
+	for _, userID := range added {
+		snapshot = append(snapshot, s.compute(userID))
+	}
+	s.send(client, types.NewMessageEventPayload(types.PresenceChanged, marshalEventData(snapshot)))
+	return nil
+}
+
+func (s *PresenceService) canSubscribe(userID, targetID string) bool {
+	if userID == targetID {
+		return true
+	}
+	if s.isContact != nil && s.isContact(userID, targetID) {
+		return true
+	}
+	return s.hub.SharesConversation(userID, targetID)
+}
+
+func (s *PresenceService) unsubscribeNoLock(client *types.Client, userID string) {
+	delete(s.subscriptions[client], userID)
+	if clients, ok := s.subscribers[userID]; ok {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(s.subscribers, userID)
+		}
+	}
+}
+
+// Presence 返回其他用户看到的状态。
+func (s *PresenceService) Presence(userID string) *types.Presence {
+	return s.compute(userID)
+}
+
+// Settings 返回用户自己的状态设置，自己可以看到隐身等设置。
+func (s *PresenceService) Settings(userID string) *store.PresenceSettings {
+	return s.presences.Settings(userID)
+}
+
+// SetPresence 修改自己的状态和自定义状态，expireAt 为 0 表示自定义状态不过期。
+func (s *PresenceService) SetPresence(userID string, state types.PresenceState, statusText string, expireAt int64) (*store.PresenceSettings, error) {
+	statusText = strings.TrimSpace(statusText)
+	if userID == "" || len([]rune(statusText)) > statusTextMaxRunes {
+		return nil, InvalidPresenceError
+	}
+	switch state {
+	case types.PresenceOnline, types.PresenceAway, types.PresenceBusy, types.PresenceInvisible:
+	default:
+		return nil, InvalidPresenceError
+	}
+	if expireAt != 0 && (statusText == "" || expireAt <= time.Now().Unix()) {
+		return nil, InvalidPresenceError
+	}
+
+	settings := &store.PresenceSettings{
+		State:          state,
+		StatusText:     statusText,
+		StatusExpireAt: expireAt,
+	}
+	s.presences.Save(userID, settings)
+	s.refresh(userID)
+	return settings, nil
+}
+
+// compute 计算其他用户看到的状态：没有在线设备或隐身时为离线，
+// 设置为在线但所有设备都空闲超过 PresenceIdleAway 时为离开。
+func (s *PresenceService) compute(userID string) *types.Presence {
+	presence := &types.Presence{UserID: userID, State: types.PresenceOffline}
+
+	lastActive, online := s.hub.UserActivity(userID)
+	settings := s.presences.Settings(userID)
+	if !online || settings.State == types.PresenceInvisible {
+		return presence
+	}
+
+	presence.State = settings.State
+	if presence.State == types.PresenceOnline && time.Since(time.Unix(lastActive, 0)) >= global.PresenceIdleAway {
+		presence.State = types.PresenceAway
+	}
+	presence.StatusText = settings.StatusText
+	presence.StatusExpireAt = settings.StatusExpireAt
+	return presence
+}
+
+// refresh 重新计算用户的状态，与上次不同时加入等待通知的队列。
+func (s *PresenceService) refresh(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presence := s.compute(userID)
+	previous, ok := s.published[userID]
+	if !ok {
+		previous = &types.Presence{UserID: userID, State: types.PresenceOffline}
+	}
+	if *presence == *previous {
+		return
+	}
+	if presence.Visible() {
+		s.published[userID] = presence
+	} else {
+		delete(s.published, userID)
+	}
+
+	s.pending[userID] = presence
+	if presence.Visible() != previous.Visible() {
+		s.pendingReload = append(s.pendingReload, userID)
+	}
+}
+
+// flush 把窗口内的状态变化合并后发出：每个订阅连接最多收到一帧，旧客户端最多收到一次 ReloadUsers。
+func (s *PresenceService) flush() {
+	s.mu.Lock()
+	pending := s.pending
+	pendingReload := s.pendingReload
+	s.pending = make(map[string]*types.Presence)
+	s.pendingReload = nil
+
+	deltas := make(map[*types.Client][]*types.Presence)
+	for userID, presence := range pending {
+		for client := range s.subscribers[userID] {
+			deltas[client] = append(deltas[client], presence)
+		}
+	}
+	var legacy []*types.Client
+	if len(pendingReload) > 0 {
+		for _, client := range s.hub.AllClients() {
+			if _, ok := s.subscriptions[client]; !ok {
+				legacy = append(legacy, client)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for client, presences := range deltas {
+		s.send(client, types.NewMessageEventPayload(types.PresenceChanged, marshalEventData(presences)))
+	}
+
+	if len(legacy) == 0 {
+		return
+	}
+	// 旧客户端只把 ReloadUsers 当作刷新 /users 的信号，窗口内只有一个用户变化时保留原来的数据格式
+	var data []byte
+	if len(pendingReload) == 1 {
+		message := "offline"
+		if pending[pendingReload[0]].Visible() {
+			message = "online"
+		}
+		data = marshalEventData(map[string]string{
+			"userId":  pendingReload[0],
+			"message": message,
+		})
+	}
+	reload := types.NewMessageEvent(types.MessageTypeGlobal, types.NewMessageEventPayload(types.ReloadUsers, data))
+	marshal, err := json.Marshal(reload)
+	if err != nil {
+		log.Printf("presence json marshal error: %v", err)
+		return
+	}
+	for _, client := range legacy {
+		_ = client.SendMessage(marshal, reload)
+	}
+}
+
+func (s *PresenceService) send(client *types.Client, event *types.MessageEvent) {
+	message := types.NewSystemMessage(client.UserId, event)
+	marshal, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("presence json marshal error: %v", err)
+		return
+	}
+	if err := client.SendMessage(marshal, message); err != nil {
+		log.Printf("send presence error: UserID: %s, %v", client.UserId, err)
+	}
+}
+
+// marshalEventData 编码事件数据，失败时记录日志并返回空数据。
+func marshalEventData(v any) []byte {
+	marshal, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("presence json marshal error: %v", err)
+	}
+	return marshal
+}
@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+func TestPresenceCoalescing(t *testing.T) {
+	cases := []struct {
+		name       string
+		states     []types.PresenceState // 一个合并窗口内 alice 依次设置的状态
+		wantFrames int                   // 订阅者收到的 PresenceChanged 帧数
+		wantState  types.PresenceState   // 订阅者最后看到的状态
+		wantReload int                   // 旧客户端收到的 ReloadUsers 帧数
+	}{
+		{name: "no change", states: []types.PresenceState{types.PresenceOnline}},
+		{name: "one change", states: []types.PresenceState{types.PresenceBusy}, wantFrames: 1, wantState: types.PresenceBusy},
+		{
+			name:       "changes in one window are merged",
+			states:     []types.PresenceState{types.PresenceAway, types.PresenceBusy, types.PresenceAway},
+			wantFrames: 1,
+			wantState:  types.PresenceAway,
+		},
+		{
+			name:       "invisible looks offline",
+			states:     []types.PresenceState{types.PresenceInvisible},
+			wantFrames: 1,
+			wantState:  types.PresenceOffline,
+			wantReload: 1,
+		},
+		{
+			name:       "offline and back in one window",
+			states:     []types.PresenceState{types.PresenceInvisible, types.PresenceOnline},
+			wantFrames: 1,
+			wantState:  types.PresenceOnline,
+			wantReload: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := newTestHub()
+			s := NewPresenceService(hub, store.NewPresenceStore())
+			s.SetContactFunc(func(userID, otherID string) bool { return true })
+
+			newTestConn(t, hub, "alice", "a1")
+			watcher := newTestConn(t, hub, "watcher", "w1")
+			legacy := newTestConn(t, hub, "legacy", "l1")
+
+			// alice 上线后清空窗口，之后的通知只来自本用例的状态变化
+			s.refresh("alice")
+			s.flush()
+			if err := s.Subscribe(watcher.client, &types.Subscription{Add: []string{"alice"}}); err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+			watcher.frames(t)
+			legacy.frames(t)
+
+			for _, state := range tc.states {
+				if _, err := s.SetPresence("alice", state, "", 0); err != nil {
+					t.Fatalf("set presence %s: %v", state, err)
+				}
+			}
+			s.flush()
+
+			frames := watcher.events(t, types.PresenceChanged)
+			if len(frames) != tc.wantFrames {
+				t.Fatalf("presence frames = %d, want %d", len(frames), tc.wantFrames)
+			}
+			if tc.wantFrames > 0 {
+				var presences []*types.Presence
+				if err := json.Unmarshal(frames[len(frames)-1], &presences); err != nil {
+					t.Fatalf("unmarshal presences: %v", err)
+				}
+				if len(presences) != 1 || presences[0].UserID != "alice" || presences[0].State != tc.wantState {
+					t.Errorf("presences = %s, want alice %s", frames[len(frames)-1], tc.wantState)
+				}
+			}
+			if reload := legacy.events(t, types.ReloadUsers); len(reload) != tc.wantReload {
+				t.Errorf("reload frames = %d, want %d", len(reload), tc.wantReload)
+			}
+		})
+	}
+}
+
+func TestPresenceSubscribe(t *testing.T) {
+	// u0...uN 都是 alice 的联系人，用于测试订阅数上限
+	contacts := make([]string, global.MaxPresenceSubscriptions+1)
+	for i := range contacts {
+		contacts[i] = fmt.Sprintf("u%d", i)
+	}
+
+	cases := []struct {
+		name      string
+		initial   []string // 事先订阅的用户
+		add       []string
+		remove    []string
+		wantErr   error
+		wantCount int // 修改后订阅的用户数
+	}{
+		{name: "self", add: []string{"alice"}, wantCount: 1},
+		{name: "contact", add: []string{"bob"}, wantCount: 1},
+		{name: "shares a room", add: []string{"carol"}, wantCount: 1},
+		{name: "stranger", add: []string{"dave"}, wantErr: SubscriptionForbiddenError},
+		{name: "stranger with contact", add: []string{"bob", "dave"}, wantErr: SubscriptionForbiddenError},
+		{name: "duplicates count once", add: []string{"bob", "bob", ""}, wantCount: 1},
+		{name: "up to the limit", add: contacts[:global.MaxPresenceSubscriptions], wantCount: global.MaxPresenceSubscriptions},
+		{
+			name:      "over the limit",
+			initial:   contacts[:global.MaxPresenceSubscriptions],
+			add:       contacts[global.MaxPresenceSubscriptions:],
+			wantErr:   TooManySubscriptionsError,
+			wantCount: global.MaxPresenceSubscriptions,
+		},
+		{
+			name:      "replace at the limit",
+			initial:   contacts[:global.MaxPresenceSubscriptions],
+			remove:    contacts[:1],
+			add:       contacts[global.MaxPresenceSubscriptions:],
+			wantCount: global.MaxPresenceSubscriptions,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := newTestHub()
+			s := NewPresenceService(hub, store.NewPresenceStore())
+			s.SetContactFunc(func(userID, otherID string) bool {
+				return userID == "alice" && (otherID == "bob" || strings.HasPrefix(otherID, "u"))
+			})
+
+			alice := registerTestClient(hub, "alice", "a1")
+			carol := registerTestClient(hub, "carol", "c1")
+			hub.AddRoom(types.NewRoom("r1", "lobby", "", "carol", "carol"))
+			for _, client := range []*types.Client{alice, carol} {
+				if _, err := hub.JoinClientRoom(client, "r1", ""); err != nil {
+					t.Fatalf("join: %v", err)
+				}
+			}
+
+			if tc.initial != nil {
+				if err := s.Subscribe(alice, &types.Subscription{Add: tc.initial}); err != nil {
+					t.Fatalf("initial subscribe: %v", err)
+				}
+			}
+			err := s.Subscribe(alice, &types.Subscription{Add: tc.add, Remove: tc.remove})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("subscribe: err = %v, want %v", err, tc.wantErr)
+			}
+
+			s.mu.Lock()
+			count := len(s.subscriptions[alice])
+			s.mu.Unlock()
+			if count != tc.wantCount {
+				t.Errorf("subscriptions = %d, want %d", count, tc.wantCount)
+			}
+		})
+	}
+}
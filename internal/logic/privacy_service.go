@@ -0,0 +1,86 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+var (
+	DMNotAllowedError    = errors.New("recipient does not accept direct messages from you")
+	BlockedError         = errors.New("you have been blocked by the recipient")
+	InvalidDMPolicyError = errors.New("invalid dm policy")
+	InvalidBlockError    = errors.New("invalid block target")
+)
+
+// PrivacyService 管理用户的隐私设置和屏蔽列表，决定谁可以给用户发私聊。
+type PrivacyService struct {
+	privacy        *store.PrivacyStore
+	contactService *ContactService
+}
+
+func NewPrivacyService(privacy *store.PrivacyStore, contactService *ContactService) *PrivacyService {
+	return &PrivacyService{
+		privacy:        privacy,
+		contactService: contactService,
+	}
+}
+
+// CheckDM 判断 from 是否可以给 to 发私聊，给自己的其它设备发消息总是允许的。
+func (s *PrivacyService) CheckDM(from, to string) error {
+	if from == to {
+		return nil
+	}
+	if s.privacy.IsBlocked(to, from) {
+		return BlockedError
+	}
+	switch s.privacy.Settings(to).DMPolicy {
+	case store.DMPolicyNobody:
+		return DMNotAllowedError
+	case store.DMPolicyContacts:
+		if !s.contactService.IsContact(from, to) {
+			return DMNotAllowedError
+		}
+	}
+	return nil
+}
+
+// IsBlocked 判断 userID 是否屏蔽了 targetID。
+func (s *PrivacyService) IsBlocked(userID, targetID string) bool {
+	return s.privacy.IsBlocked(userID, targetID)
+}
+
+// CanSendDM 是 CheckDM 的布尔形式，作为 Hub 投递私聊前的检查。
+func (s *PrivacyService) CanSendDM(from, to string) bool {
+	return s.CheckDM(from, to) == nil
+}
+
+func (s *PrivacyService) Settings(userID string) *store.PrivacySettings {
+	return s.privacy.Settings(userID)
+}
+
+// SetDMPolicy 修改谁可以给自己发私聊。
+func (s *PrivacyService) SetDMPolicy(userID string, policy store.DMPolicy) (*store.PrivacySettings, error) {
+	if userID == "" || (policy != store.DMPolicyEveryone && policy != store.DMPolicyContacts && policy != store.DMPolicyNobody) {
+		return nil, InvalidDMPolicyError
+	}
+	return s.privacy.SetDMPolicy(userID, policy), nil
+}
+
+// Block 屏蔽 targetID，被屏蔽的用户不能再给自己发私聊和好友请求。
+func (s *PrivacyService) Block(userID, targetID string) error {
+	if userID == "" || targetID == "" || userID == targetID {
+		return InvalidBlockError
+	}
+	s.privacy.Block(userID, targetID)
+	return nil
+}
+
+// Unblock 取消屏蔽。
+func (s *PrivacyService) Unblock(userID, targetID string) bool {
+	return s.privacy.Unblock(userID, targetID)
+}
+
+func (s *PrivacyService) Blocked(userID string) []*store.BlockedUser {
+	return s.privacy.Blocked(userID)
+}
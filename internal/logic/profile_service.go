@@ -0,0 +1,203 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // 校验时区名，运行环境可能没有安装时区数据
+	"unicode/utf8"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/media"
+	"github.com/l-jessie/test-im/internal/model/dto"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+var (
+	InvalidProfileError = errors.New("invalid profile")
+	AvatarNotFindError  = errors.New("avatar not find")
+)
+
+// ProfileService 管理用户资料和头像，资料变化时通知联系人以及同房间、同群组的用户。
+type ProfileService struct {
+	hub              *types.Hub
+	profiles         *store.ProfileStore
+	blobs            store.BlobStore
+	directoryService *DirectoryService
+	contactService   *ContactService
+}
+
+func NewProfileService(hub *types.Hub, profiles *store.ProfileStore, blobs store.BlobStore, directoryService *DirectoryService, contactService *ContactService) *ProfileService {
+	return &ProfileService{
+		hub:              hub,
+		profiles:         profiles,
+		blobs:            blobs,
+		directoryService: directoryService,
+		contactService:   contactService,
+	}
+}
+
+// Get 返回用户资料，只有连接过的用户才有资料。
+func (s *ProfileService) Get(userID string) (*dto.UserVO, error) {
+	return s.directoryService.Get(userID)
+}
+
+// Update 修改显示名称、简介和时区，参数为 nil 时保持原值。
+func (s *ProfileService) Update(userID string, displayName, bio, timezone *string) (*dto.UserVO, error) {
+	if _, err := s.directoryService.Get(userID); err != nil {
+		return nil, err
+	}
+	if displayName != nil {
+		trimmed := strings.TrimSpace(*displayName)
+		if utf8.RuneCountInString(trimmed) > global.MaxDisplayNameLength {
+			return nil, InvalidProfileError
+		}
+		displayName = &trimmed
+	}
+	if bio != nil && utf8.RuneCountInString(*bio) > global.MaxProfileBioLength {
+		return nil, InvalidProfileError
+	}
+	if timezone != nil && *timezone != "" {
+		if _, err := time.LoadLocation(*timezone); err != nil {
+			return nil, InvalidProfileError
+		}
+	}
+
+	s.profiles.Update(userID, func(profile *store.Profile) {
+		if displayName != nil {
+			profile.DisplayName = *displayName
+		}
+		if bio != nil {
+			profile.Bio = *bio
+		}
+		if timezone != nil {
+			profile.Timezone = *timezone
+		}
+		profile.UpdateTime = time.Now().Unix()
+	})
+	return s.notifyUpdated(userID)
+}
+
+// SetAvatar 保存上传的头像。头像缩放为缩略图大小后保存，同时去除了原图的元数据。
+func (s *ProfileService) SetAvatar(userID string, header *multipart.FileHeader) (*dto.UserVO, error) {
+	if _, err := s.directoryService.Get(userID); err != nil {
+		return nil, err
+	}
+	if header.Size > global.MaxAvatarSize {
+		return nil, FileTooLargeError
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, global.MaxAvatarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > global.MaxAvatarSize {
+		return nil, FileTooLargeError
+	}
+	mime := http.DetectContentType(data)
+	if !media.IsImageMIME(mime) {
+		return nil, InvalidImageError
+	}
+	info, err := media.ProcessImage(data, mime)
+	if err != nil {
+		return nil, InvalidImageError
+	}
+
+	key, size, err := s.blobs.Put(bytes.NewReader(info.Thumbnail))
+	if err != nil {
+		return nil, err
+	}
+	var previous *store.Blob
+	s.profiles.Update(userID, func(profile *store.Profile) {
+		previous = profile.Avatar
+		profile.Avatar = &store.Blob{Key: key, MIME: info.ThumbMIME, Size: size}
+		profile.UpdateTime = time.Now().Unix()
+	})
+	s.releaseAvatar(previous)
+	return s.notifyUpdated(userID)
+}
+
+// RemoveAvatar 删除头像。
+func (s *ProfileService) RemoveAvatar(userID string) (*dto.UserVO, error) {
+	if _, err := s.directoryService.Get(userID); err != nil {
+		return nil, err
+	}
+
+	var previous *store.Blob
+	s.profiles.Update(userID, func(profile *store.Profile) {
+		previous = profile.Avatar
+		profile.Avatar = nil
+		profile.UpdateTime = time.Now().Unix()
+	})
+	s.releaseAvatar(previous)
+	return s.notifyUpdated(userID)
+}
+
+// releaseAvatar 释放被替换或删除的头像数据。
+func (s *ProfileService) releaseAvatar(avatar *store.Blob) {
+	if avatar == nil {
+		return
+	}
+	if err := s.blobs.Delete(avatar.Key); err != nil {
+		log.Printf("delete avatar blob error: %s, %v", avatar.Key, err)
+	}
+}
+
+// OpenAvatar 打开用户头像，头像对所有用户可见。
+func (s *ProfileService) OpenAvatar(userID string) (*store.Blob, io.ReadCloser, error) {
+	avatar := s.profiles.Get(userID).Avatar
+	if avatar == nil {
+		return nil, nil, AvatarNotFindError
+	}
+
+	reader, err := s.blobs.Open(avatar.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return avatar, reader, nil
+}
+
+// notifyUpdated 把新的资料发给用户自己的其他设备、联系人，以及同房间和同群组的用户。
+func (s *ProfileService) notifyUpdated(userID string) (*dto.UserVO, error) {
+	user, err := s.directoryService.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	marshal, err := json.Marshal(user)
+	if err != nil {
+		log.Printf("profile json marshal error: %v", err)
+		return user, nil
+	}
+
+	recipients := map[string]bool{userID: true}
+	for _, contactID := range s.contactService.ContactIDs(userID) {
+		recipients[contactID] = true
+	}
+	for _, memberID := range s.hub.RoomUserIDs(s.hub.UserRoomIDs(userID)) {
+		recipients[memberID] = true
+	}
+	for _, group := range s.hub.UserGroups(userID) {
+		for _, memberID := range group.MemberIDs() {
+			recipients[memberID] = true
+		}
+	}
+
+	event := types.NewMessageEventPayload(types.ProfileUpdated, marshal)
+	for recipientID := range recipients {
+		s.hub.Broadcast <- types.NewSystemMessage(recipientID, event)
+	}
+	return user, nil
+}
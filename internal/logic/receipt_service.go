@@ -0,0 +1,109 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+var (
+	MessageNotFindError = errors.New("message not find")
+	NotParticipantError = errors.New("not a participant of the conversation")
+	InvalidReceiptError = errors.New("invalid receipt")
+)
+
+type ReceiptService struct {
+	hub       *types.Hub
+	messages  *store.MessageStore
+	receipts  *store.ReceiptStore
+	delivered chan *types.Message // 等待投递的送达回执
+}
+
+func NewReceiptService(hub *types.Hub, messages *store.MessageStore, receipts *store.ReceiptStore) *ReceiptService {
+	return &ReceiptService{
+		hub:       hub,
+		messages:  messages,
+		receipts:  receipts,
+		delivered: make(chan *types.Message, global.DeliveredQueueSize),
+	}
+}
+
+// Run 把送达回执交给 hub 投递。
+func (s *ReceiptService) Run() {
+	for message := range s.delivered {
+		s.hub.Broadcast <- message
+	}
+}
+
+// HandleDelivered 在私聊消息写入接收方连接后，向发送方推送送达回执。
+// 它在连接的写协程中调用，不能等待 hub，队列已满时丢弃回执。
+func (s *ReceiptService) HandleDelivered(client *types.Client, message *types.Message) {
+	receipt := types.NewReceipt(types.ReceiptDelivered, message, client.UserId, client.DeviceId)
+	select {
+	case s.delivered <- types.NewReceiptMessage(client.UserId, message.From, receipt):
+	default:
+		log.Printf("delivered receipt dropped: UserID: %s, MessageID: %s", client.UserId, message.ID)
+	}
+}
+
+// MarkRead 处理客户端上报的已读回执，推进已读水位并通知被读消息的发送者。
+func (s *ReceiptService) MarkRead(client *types.Client, receipt *types.Receipt) error {
+	if receipt == nil || receipt.MessageID == "" {
+		return InvalidReceiptError
+	}
+
+	message, ok := s.messages.Get(receipt.MessageID)
+	if !ok {
+		return MessageNotFindError
+	}
+	conversationID := types.ConversationIDOf(message)
+	if !canAccessConversation(s.hub, client.UserId, conversationID) {
+		return NotParticipantError
+	}
+
+	previous, moved := s.receipts.MarkRead(conversationID, client.UserId, message.ID, message.Seq)
+	if !moved {
+		return nil
+	}
+	s.syncReadState(client.UserId, conversationID)
+
+	// 通知新读到的这段消息的所有发送者（不包括自己）
+	notified := make(map[string]bool)
+	for _, read := range s.messages.Range(conversationID, previous, message.Seq) {
+		if read.From == client.UserId || notified[read.From] {
+			continue
+		}
+		notified[read.From] = true
+
+		readReceipt := types.NewReceipt(types.ReceiptRead, message, client.UserId, client.DeviceId)
+		s.hub.Broadcast <- types.NewReceiptMessage(client.UserId, read.From, readReceipt)
+	}
+	return nil
+}
+
+// syncReadState 把新的已读水位同步给该用户的所有设备。
+func (s *ReceiptService) syncReadState(userID, conversationID string) {
+	mark, ok := s.receipts.ReadWatermark(conversationID, userID)
+	if !ok {
+		return
+	}
+	marshal, err := json.Marshal(mark)
+	if err != nil {
+		log.Printf("read state json marshal error: %v", err)
+		return
+	}
+	s.hub.Broadcast <- types.NewSystemMessage(userID, types.NewMessageEventPayload(types.ReadStateSync, marshal))
+}
+
+// RoomWatermarks 返回房间内所有用户的已读水位，只有房间成员可以查看。
+func (s *ReceiptService) RoomWatermarks(userID, roomID string) ([]*store.Watermark, error) {
+	conversationID := types.RoomConversationID(roomID)
+	if !canAccessConversation(s.hub, userID, conversationID) {
+		return nil, NotParticipantError
+	}
+	return s.receipts.ReadWatermarks(conversationID), nil
+}
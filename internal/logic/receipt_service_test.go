@@ -0,0 +1,126 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+func TestReceiptWatermark(t *testing.T) {
+	cases := []struct {
+		name        string
+		from        []string // 私聊中依次发送消息的用户，顺序号从 1 开始
+		reader      string
+		reads       []int // 依次上报已读的消息顺序号，0 表示不存在的消息
+		wantErr     error // 最后一次上报的错误
+		wantSeq     int64 // reader 最后的已读水位
+		wantReceipt int   // bob 收到的已读回执数
+		wantSync    int   // reader 的设备收到的水位同步数
+	}{
+		{
+			name:        "read moves the watermark",
+			from:        []string{"bob", "bob", "alice"},
+			reader:      "alice",
+			reads:       []int{2},
+			wantSeq:     2,
+			wantReceipt: 1,
+			wantSync:    1,
+		},
+		{
+			name:        "watermark never moves back",
+			from:        []string{"bob", "bob", "bob"},
+			reader:      "alice",
+			reads:       []int{3, 1, 2},
+			wantSeq:     3,
+			wantReceipt: 1,
+			wantSync:    1,
+		},
+		{
+			name:        "each sender is notified once per read",
+			from:        []string{"bob", "bob", "bob"},
+			reader:      "alice",
+			reads:       []int{1, 3},
+			wantSeq:     3,
+			wantReceipt: 2,
+			wantSync:    2,
+		},
+		{
+			name:     "own messages are not receipted",
+			from:     []string{"alice", "alice"},
+			reader:   "alice",
+			reads:    []int{2},
+			wantSeq:  2,
+			wantSync: 1,
+		},
+		{
+			name:    "unknown message",
+			from:    []string{"bob"},
+			reader:  "alice",
+			reads:   []int{0},
+			wantErr: MessageNotFindError,
+		},
+		{
+			name:    "not a participant",
+			from:    []string{"bob"},
+			reader:  "carol",
+			reads:   []int{1},
+			wantErr: NotParticipantError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := newTestHub()
+			messages := store.NewMessageStore()
+			receipts := store.NewReceiptStore()
+			s := NewReceiptService(hub, messages, receipts)
+
+			reader := newTestConn(t, hub, tc.reader, "r1")
+			bob := newTestConn(t, hub, "bob", "b1")
+
+			conversationID := types.DirectConversationID("alice", "bob")
+			saved := make([]*types.Message, 0, len(tc.from))
+			for i, from := range tc.from {
+				to := "bob"
+				if from == "bob" {
+					to = "alice"
+				}
+				message := types.NewMessage(types.MessageTypeUser, types.NewPayload(types.PayloadTypeText, []byte(`"hi"`)), from, to)
+				message.ID = fmt.Sprintf("m%d", i+1)
+				messages.Save(conversationID, message)
+				saved = append(saved, message)
+			}
+
+			var err error
+			for _, seq := range tc.reads {
+				messageID := "missing"
+				if seq > 0 {
+					messageID = saved[seq-1].ID
+				}
+				err = s.MarkRead(reader.client, &types.Receipt{Type: types.ReceiptRead, MessageID: messageID})
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("mark read: err = %v, want %v", err, tc.wantErr)
+			}
+
+			if seq := receipts.ReadSeq(conversationID, tc.reader); seq != tc.wantSeq {
+				t.Errorf("watermark = %d, want %d", seq, tc.wantSeq)
+			}
+			var receiptCount int
+			for _, message := range bob.frames(t) {
+				if message.Type == types.MessageTypeReceipt && message.Receipt.Type == types.ReceiptRead {
+					receiptCount++
+				}
+			}
+			if receiptCount != tc.wantReceipt {
+				t.Errorf("read receipts = %d, want %d", receiptCount, tc.wantReceipt)
+			}
+			if sync := reader.events(t, types.ReadStateSync); len(sync) != tc.wantSync {
+				t.Errorf("read state syncs = %d, want %d", len(sync), tc.wantSync)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	InvalidRoomCommandError = errors.New("invalid room command")
+)
+
+// RoomService 处理客户端通过 websocket 发来的房间命令。
+// 命令作用于发送命令的连接本身，不需要再按用户ID和设备ID查找连接。
+type RoomService struct {
+	hub *types.Hub
+}
+
+func NewRoomService(hub *types.Hub) *RoomService {
+	return &RoomService{hub: hub}
+}
+
+// Handle 执行房间命令并返回命令完成后的房间。
+func (s *RoomService) Handle(client *types.Client, messageType types.MessageType, command *types.RoomCommand) (*types.RoomSummary, error) {
+	if command == nil {
+		return nil, InvalidRoomCommandError
+	}
+
+	switch messageType {
+	case types.MessageTypeCreateRoom:
+		name := strings.TrimSpace(command.Name)
+		if name == "" {
+			return nil, InvalidRoomCommandError
+		}
+		room := types.NewRoom(utils.GenerateUUID(), name, command.Password, client.UserId, client.UserName)
+		return s.hub.AddRoom(room), nil
+	case types.MessageTypeJoinRoom:
+		return s.hub.JoinClientRoom(client, command.RoomID, command.Password)
+	case types.MessageTypeLeaveRoom:
+		return s.hub.LeaveClientRoom(client, command.RoomID)
+	default:
+		return nil, InvalidRoomCommandError
+	}
+}
@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	ScheduleNotFindError    = errors.New("scheduled message not find")
+	InvalidSendTimeError    = errors.New("invalid send time")
+	InvalidMessageTypeError = errors.New("invalid message type")
+)
+
+// ScheduleService 保存定时消息，并在到达计划时间时通过 ChatService 投递到 Hub。
+type ScheduleService struct {
+	hub         *types.Hub
+	chatService *ChatService
+	schedules   *store.ScheduleStore
+}
+
+func NewScheduleService(hub *types.Hub, chatService *ChatService, schedules *store.ScheduleStore) *ScheduleService {
+	return &ScheduleService{
+		hub:         hub,
+		chatService: chatService,
+		schedules:   schedules,
+	}
+}
+
+// Create 创建一条定时消息。
+func (s *ScheduleService) Create(userID string, messageType types.MessageType, to string, payload *types.Payload, ttl, sendAt int64) (*store.ScheduledMessage, error) {
+	if payload == nil {
+		return nil, InvalidMessageTypeError
+	}
+	if err := s.checkTarget(userID, messageType, to); err != nil {
+		return nil, err
+	}
+	if err := checkSendAt(sendAt); err != nil {
+		return nil, err
+	}
+	if err := s.checkPayload(userID, messageType, to, payload); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	schedule := &store.ScheduledMessage{
+		ID:         utils.GenerateUUID(),
+		UserID:     userID,
+		Type:       messageType,
+		To:         to,
+		Payload:    payload,
+		TTL:        ttl,
+		SendAt:     sendAt,
+		Status:     store.SchedulePending,
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	s.schedules.Save(schedule)
+	return schedule, nil
+}
+
+// List 返回用户的所有定时消息。
+func (s *ScheduleService) List(userID string) []*store.ScheduledMessage {
+	return s.schedules.ListByUser(userID)
+}
+
+// Update 修改尚未发送的定时消息，payload 为空或 sendAt 为 0 时保持原值。
+func (s *ScheduleService) Update(userID, scheduleID string, payload *types.Payload, sendAt int64) (*store.ScheduledMessage, error) {
+	if sendAt != 0 {
+		if err := checkSendAt(sendAt); err != nil {
+			return nil, err
+		}
+	}
+	current, err := s.owned(userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		if err := s.checkPayload(userID, current.Type, current.To, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	schedule, ok := s.schedules.UpdatePending(scheduleID, func(schedule *store.ScheduledMessage) {
+		if payload != nil {
+			schedule.Payload = payload
+		}
+		if sendAt != 0 {
+			schedule.SendAt = sendAt
+		}
+		schedule.UpdateTime = time.Now().Unix()
+	})
+	if !ok {
+		return nil, ScheduleNotFindError
+	}
+	return schedule, nil
+}
+
+// Cancel 取消尚未发送的定时消息。
+func (s *ScheduleService) Cancel(userID, scheduleID string) (*store.ScheduledMessage, error) {
+	if _, err := s.owned(userID, scheduleID); err != nil {
+		return nil, err
+	}
+
+	schedule, ok := s.schedules.UpdatePending(scheduleID, func(schedule *store.ScheduledMessage) {
+		schedule.Status = store.ScheduleCancelled
+		schedule.UpdateTime = time.Now().Unix()
+	})
+	if !ok {
+		return nil, ScheduleNotFindError
+	}
+	return schedule, nil
+}
+
+// Run 定期检查到期的定时消息并发送。
+func (s *ScheduleService) Run() {
+	ticker := time.NewTicker(global.ScheduleCheckPeriod)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, schedule := range s.schedules.ClaimDue(now.Unix()) {
+			s.send(schedule)
+		}
+	}
+}
+
+func (s *ScheduleService) send(schedule *store.ScheduledMessage) {
+	// 发送时重新校验，用户可能已经离开了房间
+	err := s.checkTarget(schedule.UserID, schedule.Type, schedule.To)
+	// 发送过程会修改 payload，使用副本避免消息与定时消息记录共享同一个 payload
+	message := types.NewMessage(schedule.Type, schedule.Payload.Clone(), schedule.UserID, schedule.To)
+	message.TTL = schedule.TTL
+	if err == nil {
+		err = s.chatService.Send(message)
+	}
+
+	schedule.MessageID = message.ID
+	if err != nil {
+		log.Printf("send scheduled message error: %s, %v", schedule.ID, err)
+		schedule.Status = store.ScheduleFailed
+		schedule.Error = err.Error()
+	}
+	s.schedules.Save(schedule)
+}
+
+// checkTarget 校验用户可以向目标会话发送消息。
+func (s *ScheduleService) checkTarget(userID string, messageType types.MessageType, to string) error {
+	if messageType != types.MessageTypeRoom && messageType != types.MessageTypeUser && messageType != types.MessageTypeGroup {
+		return InvalidMessageTypeError
+	}
+	conversationID := types.ConversationIDOf(&types.Message{Type: messageType, From: userID, To: to})
+	if to == "" || !canAccessConversation(s.hub, userID, conversationID) {
+		return NotParticipantError
+	}
+	return nil
+}
+
+// checkPayload 按发送时的规则校验消息内容，调用前需要先校验目标会话。
+func (s *ScheduleService) checkPayload(userID string, messageType types.MessageType, to string, payload *types.Payload) error {
+	conversationID := types.ConversationIDOf(&types.Message{Type: messageType, From: userID, To: to})
+	return s.chatService.ValidatePayload(conversationID, payload)
+}
+
+// owned 返回用户自己的定时消息。
+func (s *ScheduleService) owned(userID, scheduleID string) (*store.ScheduledMessage, error) {
+	schedule, ok := s.schedules.Get(scheduleID)
+	if !ok || schedule.UserID != userID {
+		return nil, ScheduleNotFindError
+	}
+	return schedule, nil
+}
+
+func checkSendAt(sendAt int64) error {
+	now := time.Now()
+	if sendAt <= now.Unix() || sendAt > now.Add(global.MaxScheduleAhead).Unix() {
+		return InvalidSendTimeError
+	}
+	return nil
+}
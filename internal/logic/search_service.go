@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+)
+
+var (
+	EmptySearchQueryError = errors.New("empty search query")
+)
+
+type SearchService struct {
+	hub      *types.Hub
+	messages *store.MessageStore
+}
+
+func NewSearchService(hub *types.Hub, messages *store.MessageStore) *SearchService {
+	return &SearchService{
+		hub:      hub,
+		messages: messages,
+	}
+}
+
+// Search 在用户所在的房间和私聊中搜索消息文本。
+func (s *SearchService) Search(userID string, query *store.SearchQuery) ([]*types.Message, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return nil, EmptySearchQueryError
+	}
+	if query.Limit <= 0 {
+		query.Limit = searchDefaultLimit
+	} else if query.Limit > searchMaxLimit {
+		query.Limit = searchMaxLimit
+	}
+
+	// 同一次搜索中缓存会话的访问权限，避免重复加锁查询 hub
+	allowed := make(map[string]bool)
+	return s.messages.Search(query, func(conversationID string) bool {
+		allow, ok := allowed[conversationID]
+		if !ok {
+			allow = canAccessConversation(s.hub, userID, conversationID)
+			allowed[conversationID] = allow
+		}
+		return allow
+	}), nil
+}
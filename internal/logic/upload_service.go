@@ -0,0 +1,224 @@
+package logic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/l-jessie/test-im/internal/global"
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+	"github.com/l-jessie/test-im/internal/utils"
+)
+
+var (
+	UploadNotFindError       = errors.New("upload not find")
+	UploadOffsetError        = errors.New("upload offset mismatch")
+	UploadChunkTooLargeError = errors.New("upload chunk too large")
+	ChecksumMismatchError    = errors.New("checksum mismatch")
+)
+
+// UploadService 实现基于偏移量的可续传分块上传。
+// 客户端创建上传后按顺序提交分块，每个分块带有起始偏移量和 sha256 校验值，
+// 断线后查询已接收的偏移量即可从该位置继续。全部接收后文件进入 FileService，
+// 得到的文件ID可以作为 PayloadTypeFile/PayloadTypeImage 消息的 FileID。
+type UploadService struct {
+	fileService *FileService
+	uploads     *store.UploadStore
+	dir         string
+}
+
+func NewUploadService(fileService *FileService, uploads *store.UploadStore, dir string) (*UploadService, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &UploadService{
+		fileService: fileService,
+		uploads:     uploads,
+		dir:         dir,
+	}, nil
+}
+
+// Create 创建一个上传会话。checksum 为整个文件的 sha256 十六进制值，可以为空。
+func (s *UploadService) Create(uploaderID string, messageType types.MessageType, to, name string, size int64, checksum string) (*store.UploadSession, error) {
+	if size <= 0 || size > global.MaxResumableUploadSize {
+		return nil, FileTooLargeError
+	}
+
+	conversationID, err := s.fileService.uploadConversation(uploaderID, messageType, to)
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeExpired()
+
+	id := utils.GenerateUUID()
+	partPath := filepath.Join(s.dir, id+".part")
+	part, err := os.Create(partPath)
+	if err != nil {
+		return nil, err
+	}
+	_ = part.Close()
+
+	now := time.Now()
+	session := &store.UploadSession{
+		ID:             id,
+		UploaderID:     uploaderID,
+		ConversationID: conversationID,
+		Name:           name,
+		Size:           size,
+		Checksum:       checksum,
+		PartPath:       partPath,
+		CreateTime:     now,
+		UpdateTime:     now,
+	}
+	s.uploads.Save(session)
+	return session, nil
+}
+
+// Get 返回上传会话，只有上传者本人可以查看。
+func (s *UploadService) Get(uploaderID, uploadID string) (*store.UploadSession, error) {
+	session, ok := s.uploads.Get(uploadID)
+	if !ok || session.UploaderID != uploaderID {
+		return nil, UploadNotFindError
+	}
+	return session, nil
+}
+
+// WriteChunk 在 offset 处追加一个分块，checksum 为该分块的 sha256 值。
+// 返回新的偏移量；当最后一个分块写入后，返回生成的文件记录。
+func (s *UploadService) WriteChunk(uploaderID, uploadID string, offset int64, checksum []byte, chunk io.Reader) (int64, *store.FileRecord, error) {
+	session, err := s.Get(uploaderID, uploadID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	// 会话可能在等待锁的期间已经完成或被取消
+	if _, ok := s.uploads.Get(uploadID); !ok {
+		return 0, nil, UploadNotFindError
+	}
+	if offset != session.Offset {
+		return session.Offset, nil, UploadOffsetError
+	}
+
+	data, err := io.ReadAll(io.LimitReader(chunk, global.MaxUploadChunkSize+1))
+	if err != nil {
+		return session.Offset, nil, err
+	}
+	if len(data) > global.MaxUploadChunkSize {
+		return session.Offset, nil, UploadChunkTooLargeError
+	}
+	if session.Offset+int64(len(data)) > session.Size {
+		return session.Offset, nil, FileTooLargeError
+	}
+	sum := sha256.Sum256(data)
+	if len(checksum) == 0 || !bytes.Equal(sum[:], checksum) {
+		return session.Offset, nil, ChecksumMismatchError
+	}
+
+	if err := appendFile(session.PartPath, session.Offset, data); err != nil {
+		return session.Offset, nil, err
+	}
+	session.Offset += int64(len(data))
+	session.UpdateTime = time.Now()
+
+	if session.Offset < session.Size {
+		return session.Offset, nil, nil
+	}
+
+	record, err := s.complete(session)
+	return session.Offset, record, err
+}
+
+// Cancel 取消上传并删除已接收的数据。
+func (s *UploadService) Cancel(uploaderID, uploadID string) error {
+	session, err := s.Get(uploaderID, uploadID)
+	if err != nil {
+		return err
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	s.remove(session)
+	return nil
+}
+
+// complete 校验整个文件后交给 FileService 保存，调用方需持有会话的锁。
+func (s *UploadService) complete(session *store.UploadSession) (*store.FileRecord, error) {
+	part, err := os.Open(session.PartPath)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+
+	if session.Checksum != "" {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, part); err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != session.Checksum {
+			// 整体校验失败说明数据已不可信，只能重新上传
+			s.remove(session)
+			return nil, ChecksumMismatchError
+		}
+		if _, err := part.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	// 无论保存是否成功都删除会话：已接收完的会话无法再写入分块，保留下来只能等到过期，
+	// 保存失败（例如图片无法解析）时客户端需要重新上传
+	record, err := s.fileService.save(session.UploaderID, session.ConversationID, session.Name, part, session.Size)
+	s.remove(session)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *UploadService) remove(session *store.UploadSession) {
+	s.uploads.Delete(session.ID)
+	if err := os.Remove(session.PartPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("remove upload part error: %v", err)
+	}
+}
+
+// removeExpired 清理长时间没有进展的上传。
+// UpdateTime 在会话锁内读取，已经完成、取消或刚刚写入过分块的会话会被跳过。
+func (s *UploadService) removeExpired() {
+	cutoff := time.Now().Add(-global.UploadExpire)
+	for _, session := range s.uploads.List() {
+		session.Lock()
+		if current, ok := s.uploads.Get(session.ID); ok && current == session && session.UpdateTime.Before(cutoff) {
+			s.remove(session)
+		}
+		session.Unlock()
+	}
+}
+
+// appendFile 在 offset 处写入数据，并截断之后可能残留的半个分块。
+func appendFile(path string, offset int64, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, offset); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Truncate(offset + int64(len(data))); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+// uploadStep 提交 content[start:end]，offset 为声明的起始偏移量。
+type uploadStep struct {
+	user        string
+	offset      int64
+	start, end  int
+	badChecksum bool
+
+	wantOffset int64
+	wantErr    error
+	wantDone   bool
+}
+
+func TestUploadResume(t *testing.T) {
+	content := []byte("hello, this is a resumable upload")
+	size := int64(len(content))
+
+	cases := []struct {
+		name       string
+		checksum   string // 整个文件的校验值，"bad" 表示错误的值
+		steps      []uploadStep
+		wantClosed bool // 最后会话是否已不存在
+	}{
+		{
+			name: "in order",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: 10, wantOffset: 10},
+				{user: "alice", offset: 10, start: 10, end: len(content), wantOffset: size, wantDone: true},
+			},
+			wantClosed: true,
+		},
+		{
+			name: "resume after a lost response",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: 10, wantOffset: 10},
+				// 客户端没有收到应答，重发了同一个分块
+				{user: "alice", offset: 0, start: 0, end: 10, wantOffset: 10, wantErr: UploadOffsetError},
+				{user: "alice", offset: 10, start: 10, end: 20, wantOffset: 20},
+				{user: "alice", offset: 20, start: 20, end: len(content), wantOffset: size, wantDone: true},
+			},
+			wantClosed: true,
+		},
+		{
+			name: "corrupted chunk is retried",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: 10, badChecksum: true, wantOffset: 0, wantErr: ChecksumMismatchError},
+				{user: "alice", offset: 0, start: 0, end: len(content), wantOffset: size, wantDone: true},
+			},
+			wantClosed: true,
+		},
+		{
+			name:     "whole file checksum",
+			checksum: "sha256",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: len(content), wantOffset: size, wantDone: true},
+			},
+			wantClosed: true,
+		},
+		{
+			name:     "whole file checksum mismatch",
+			checksum: "bad",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: len(content), wantOffset: size, wantErr: ChecksumMismatchError},
+			},
+			wantClosed: true,
+		},
+		{
+			name: "chunk past the declared size",
+			steps: []uploadStep{
+				{user: "alice", offset: 0, start: 0, end: 10, wantOffset: 10},
+				{user: "alice", offset: 10, start: 0, end: len(content), wantOffset: 10, wantErr: FileTooLargeError},
+			},
+		},
+		{
+			name: "other users cannot write",
+			steps: []uploadStep{
+				{user: "bob", offset: 0, start: 0, end: 10, wantErr: UploadNotFindError},
+				{user: "alice", offset: 0, start: 0, end: 10, wantOffset: 10},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, fileService := newTestUploadService(t)
+
+			checksum := ""
+			switch tc.checksum {
+			case "sha256":
+				sum := sha256.Sum256(content)
+				checksum = hex.EncodeToString(sum[:])
+			case "bad":
+				checksum = hex.EncodeToString(make([]byte, sha256.Size))
+			}
+			session, err := s.Create("alice", types.MessageTypeUser, "carol", "a.txt", size, checksum)
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+
+			var record *store.FileRecord
+			for i, step := range tc.steps {
+				chunk := content[step.start:step.end]
+				sum := sha256.Sum256(chunk)
+				if step.badChecksum {
+					sum[0] ^= 0xff
+				}
+
+				offset, stepRecord, err := s.WriteChunk(step.user, session.ID, step.offset, sum[:], bytes.NewReader(chunk))
+				if !errors.Is(err, step.wantErr) {
+					t.Fatalf("step %d: err = %v, want %v", i, err, step.wantErr)
+				}
+				if offset != step.wantOffset {
+					t.Errorf("step %d: offset = %d, want %d", i, offset, step.wantOffset)
+				}
+				if (stepRecord != nil) != step.wantDone {
+					t.Fatalf("step %d: record = %v, want done %v", i, stepRecord, step.wantDone)
+				}
+				if stepRecord != nil {
+					record = stepRecord
+				}
+			}
+
+			if _, err := s.Get("alice", session.ID); errors.Is(err, UploadNotFindError) != tc.wantClosed {
+				t.Errorf("session closed = %v, want %v", err != nil, tc.wantClosed)
+			}
+			if record == nil {
+				return
+			}
+
+			_, reader, err := fileService.Open("alice", record.ID)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(data) != string(content) || record.Size != size {
+				t.Errorf("file = %q (%d bytes), want %q", data, record.Size, content)
+			}
+		})
+	}
+}
+
+func newTestUploadService(t *testing.T) (*UploadService, *FileService) {
+	t.Helper()
+
+	dir := t.TempDir()
+	blobs, err := store.NewLocalBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("blob store: %v", err)
+	}
+	fileService := NewFileService(newTestHub(), blobs, store.NewFileStore())
+	s, err := NewUploadService(fileService, store.NewUploadStore(), filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatalf("upload service: %v", err)
+	}
+	return s, fileService
+}
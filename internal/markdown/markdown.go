@@ -0,0 +1,89 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	UnsafeMarkdownError = errors.New("unsafe markdown")
+)
+
+// 只允许这些协议的链接，其它协议（javascript:、data: 等）一律拒绝
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// 图片只允许引用本服务上传的文件，避免通过外链图片追踪用户
+const imagePathPrefix = "/v1/api/files/"
+
+// md 使用 CommonMark 加删除线扩展，渲染器保持默认的安全模式：
+// 原始 HTML 会被丢弃，危险链接会被置空。
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.Strikethrough),
+)
+
+// Render 把 Markdown 解析为 HTML。
+// 包含原始 HTML 或不安全链接的内容会被直接拒绝，而不是静默删除，
+// 让发送者知道消息没有按原样发出。
+func Render(source string) (string, error) {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+	if err := check(doc, src); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// check 遍历语法树，拒绝原始 HTML 和不在白名单中的链接。
+func check(doc ast.Node, source []byte) error {
+	return ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkStop, UnsafeMarkdownError
+		case *ast.Link:
+			if !safeLink(string(n.Destination)) {
+				return ast.WalkStop, UnsafeMarkdownError
+			}
+		case *ast.AutoLink:
+			if !safeLink(string(n.URL(source))) {
+				return ast.WalkStop, UnsafeMarkdownError
+			}
+		case *ast.Image:
+			if !strings.HasPrefix(string(n.Destination), imagePathPrefix) {
+				return ast.WalkStop, UnsafeMarkdownError
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func safeLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	// 邮箱形式的自动链接没有协议
+	if u.Scheme == "" {
+		return !strings.ContainsAny(link, ":\\")
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
@@ -0,0 +1,132 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	ThumbnailMaxSize = 320 // 缩略图最长边的像素数
+	MaxImagePixels   = 64 << 20
+)
+
+var (
+	UnsupportedImageError = errors.New("unsupported image format")
+	ImageTooLargeError    = errors.New("image dimensions too large")
+)
+
+// ImageInfo 是解析图片后得到的元数据和缩略图。
+type ImageInfo struct {
+	MIME      string
+	Width     int
+	Height    int
+	Thumbnail []byte // 编码后的缩略图
+	ThumbMIME string
+	ThumbW    int
+	ThumbH    int
+}
+
+// IsImageMIME 判断是否是支持生成缩略图的图片类型。
+func IsImageMIME(mime string) bool {
+	switch mime {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// ProcessImage 解码图片，返回元数据与缩略图。
+// 解码前先读取尺寸，拒绝像素数过大的图片以防止内存耗尽。
+func ProcessImage(data []byte, mime string) (*ImageInfo, error) {
+	decodeConfig, decode, err := decoders(mime)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return nil, ImageTooLargeError
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		MIME:   mime,
+		Width:  config.Width,
+		Height: config.Height,
+	}
+
+	thumb := resize(img, ThumbnailMaxSize)
+	var buf bytes.Buffer
+	if mime == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+		info.ThumbMIME = "image/jpeg"
+	} else {
+		// 其它格式可能带透明通道，缩略图使用 PNG
+		err = png.Encode(&buf, thumb)
+		info.ThumbMIME = "image/png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	info.Thumbnail = buf.Bytes()
+	info.ThumbW = thumb.Bounds().Dx()
+	info.ThumbH = thumb.Bounds().Dy()
+	return info, nil
+}
+
+func decoders(mime string) (func(io.Reader) (image.Config, error), func(io.Reader) (image.Image, error), error) {
+	switch mime {
+	case "image/png":
+		return png.DecodeConfig, png.Decode, nil
+	case "image/jpeg":
+		return jpeg.DecodeConfig, jpeg.Decode, nil
+	case "image/gif":
+		// 动图只取第一帧生成缩略图
+		return gif.DecodeConfig, gif.Decode, nil
+	case "image/webp":
+		return webp.DecodeConfig, webp.Decode, nil
+	}
+	return nil, nil, UnsupportedImageError
+}
+
+// resize 按比例缩小图片，使最长边不超过 max，小图原样返回。
+func resize(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= max && height <= max {
+		return img
+	}
+
+	if width >= height {
+		height = height * max / width
+		width = max
+	} else {
+		width = width * max / height
+		height = max
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
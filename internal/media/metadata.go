@@ -0,0 +1,130 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	MalformedImageError = errors.New("malformed image")
+)
+
+// StripMetadata 移除图片中的 EXIF/XMP 元数据（其中可能包含拍摄位置），
+// 图像数据本身不重新编码。GIF 不包含此类元数据，原样返回。
+func StripMetadata(data []byte, mime string) ([]byte, error) {
+	switch mime {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+var (
+	jpegExifHeader = []byte("Exif\x00\x00")
+	jpegXMPHeader  = []byte("http://ns.adobe.com/xap/1.0/")
+)
+
+// stripJPEG 删除 APP1 段中的 EXIF 和 XMP 数据。
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, MalformedImageError
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil, MalformedImageError
+		}
+		marker := data[i+1]
+		// 图像数据开始后不再有元数据段，剩余部分原样保留
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, MalformedImageError
+		}
+		segment := data[i : i+2+length]
+		body := segment[4:]
+		if marker == 0xE1 && (bytes.HasPrefix(body, jpegExifHeader) || bytes.HasPrefix(body, jpegXMPHeader)) {
+			i += 2 + length
+			continue
+		}
+		out = append(out, segment...)
+		i += 2 + length
+	}
+	return append(out, data[i:]...), nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG 删除 eXIf 和文本块（XMP 保存在 iTXt 中）。
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, MalformedImageError
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, MalformedImageError
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "iTXt", "zTXt":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+// stripWebP 删除 EXIF 和 XMP 块，并清除 VP8X 中对应的标志位。
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, MalformedImageError
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	i := 12
+	for i+8 <= len(data) {
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		// 块数据按偶数字节对齐
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, MalformedImageError
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if size > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
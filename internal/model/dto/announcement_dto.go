@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// CreateAnnouncementRequest 三种目标只能选择一种：All、RoomIDs 或 UserIDs。
+type CreateAnnouncementRequest struct {
+	UserID  string         `json:"userId"` // 发布者，仅用于记录
+	Payload *types.Payload `json:"payload"`
+	All     bool           `json:"all"`
+	RoomIDs []string       `json:"roomIds"`
+	UserIDs []string       `json:"userIds"`
+}
+
+type AnnouncementResponse struct {
+	*types.Announcement
+	CreatorID      string   `json:"creatorId"`
+	All            bool     `json:"all"`
+	RoomIDs        []string `json:"roomIds,omitempty"`
+	UserIDs        []string `json:"userIds,omitempty"`
+	RecipientCount int      `json:"recipientCount"` // 发给所有用户时为 0
+}
@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+	"github.com/l-jessie/test-im/internal/store"
+)
+
+type SendFriendRequestRequest struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+type ContactResponse struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Online   bool            `json:"online"`
+	Presence *types.Presence `json:"presence"`
+	Since    int64           `json:"since"` // 成为联系人的时间
+}
+
+type UpdatePrivacyRequest struct {
+	DMPolicy store.DMPolicy `json:"dmPolicy"`
+}
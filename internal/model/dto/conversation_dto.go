@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type MessagePreview struct {
+	ID        string            `json:"id"`
+	From      string            `json:"from"`
+	Type      types.PayloadType `json:"type"`
+	Text      string            `json:"text"` // 预览文本，非文本消息为占位描述
+	Timestamp int64             `json:"time"`
+}
+
+type ConversationResponse struct {
+	ID          string            `json:"id"`       // 会话ID
+	Type        types.MessageType `json:"type"`     // 会话类型: 房间/私聊
+	TargetID    string            `json:"targetId"` // 房间ID 或 对方用户ID
+	Name        string            `json:"name"`
+	LastMessage *MessagePreview   `json:"lastMessage"`
+	LastReadSeq int64             `json:"lastReadSeq"`
+	UnreadCount int               `json:"unreadCount"`
+}
+
+type UpdateConversationSettingsRequest struct {
+	DisappearingSeconds int64 `json:"disappearingSeconds"` // 0 表示关闭阅后即焚
+}
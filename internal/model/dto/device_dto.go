@@ -0,0 +1,5 @@
+package dto
+
+type LinkDeviceRequest struct {
+	DeviceID string `json:"deviceId"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/entity"
+)
+
+type FileResponse struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Size       int64              `json:"size"`
+	MIME       string             `json:"mime"`
+	URL        string             `json:"url"` // 下载地址，需要带上 userId 参数
+	CreateTime entity.BizTimeFull `json:"createTime"`
+
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/entity"
+)
+
+type CreateGroupRequest struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"` // 初始成员 用户ID
+}
+
+type AddGroupMembersRequest struct {
+	Members []string `json:"members"` // 新成员 用户ID
+}
+
+type GroupResponse struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	OwnerID    string             `json:"ownerId"`    // 群主 用户ID
+	Count      int                `json:"count"`      // 成员数量
+	CreateTime entity.BizTimeFull `json:"createTime"` // 创建时间
+	Members    []*UserVO          `json:"members"`
+}
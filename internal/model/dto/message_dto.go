@@ -0,0 +1,10 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type ForwardMessageRequest struct {
+	Type types.MessageType `json:"type"` // 目标会话类型: 2 房间 / 3 私聊 / 11 群组
+	To   string            `json:"to"`   // 目标房间ID 或 用户ID
+}
@@ -7,14 +7,11 @@ import (
 type CreateRoomRequest struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
-	UserID   string `json:"userId"` // 房间拥有者 用户ID
 }
 
 type JoinRoomRequest struct {
 	RoomID   string `json:"roomId"`
 	Password string `json:"password"`
-	UserID   string `json:"userId"`
-	DeviceID string `json:"deviceId"`
 }
 
 type RoomsResponse struct {
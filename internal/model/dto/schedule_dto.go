@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type CreateScheduleRequest struct {
+	Type    types.MessageType `json:"type"` // 2 房间 / 3 私聊 / 11 群组
+	To      string            `json:"to"`
+	Payload *types.Payload    `json:"payload"`
+	TTL     int64             `json:"ttl"`
+	SendAt  int64             `json:"sendAt"` // 计划发送时间，unix 秒
+}
+
+type UpdateScheduleRequest struct {
+	Payload *types.Payload `json:"payload"` // 为空时不修改
+	SendAt  int64          `json:"sendAt"`  // 为 0 时不修改
+}
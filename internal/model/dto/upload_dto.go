@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type CreateUploadRequest struct {
+	Type     types.MessageType `json:"type"` // 2 房间 / 3 私聊 / 11 群组
+	To       string            `json:"to"`
+	Name     string            `json:"name"`
+	Size     int64             `json:"size"`
+	Checksum string            `json:"checksum"` // 整个文件的 sha256 十六进制值，可选
+}
+
+type UploadResponse struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Size      int64         `json:"size"`
+	Offset    int64         `json:"offset"`    // 已接收的字节数，续传时从这里开始
+	ChunkSize int64         `json:"chunkSize"` // 单个分块的最大字节数
+	File      *FileResponse `json:"file,omitempty"`
+}
@@ -1,6 +1,31 @@
 package dto
 
+import (
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
 type UserVO struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`        // 连接时的名称
+	DisplayName string          `json:"displayName"` // 用户设置的显示名称，未设置时与 Name 相同
+	Avatar      string          `json:"avatar,omitempty"`
+	Bio         string          `json:"bio,omitempty"`
+	Timezone    string          `json:"timezone,omitempty"`
+	Presence    *types.Presence `json:"presence,omitempty"`
+
+	FirstSeen int64 `json:"firstSeen,omitempty"` // 第一次连接的时间
+	LastSeen  int64 `json:"lastSeen,omitempty"`  // 最后在线时间，在线时为空
+}
+
+// UpdateProfileRequest 修改用户资料，为空的字段保持原值，传空字符串表示清除。
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+	Timezone    *string `json:"timezone"`
+}
+
+type UpdatePresenceRequest struct {
+	State          types.PresenceState `json:"state"`
+	StatusText     string              `json:"statusText"`
+	StatusExpireAt int64               `json:"statusExpireAt"` // 自定义状态的过期时间，0 表示不过期
 }
@@ -0,0 +1,8 @@
+package types
+
+// Announcement 是管理员发布的公告，通过 Announcement 事件推送给目标用户。
+type Announcement struct {
+	ID         string   `json:"id"`
+	Payload    *Payload `json:"payload"`
+	CreateTime int64    `json:"createTime"`
+}
@@ -1,8 +1,11 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/l-jessie/test-im/internal/global"
@@ -10,25 +13,128 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// outbound 是等待写入连接的一帧数据，requestID 只用于 v2 协议的应答。
+type outbound struct {
+	frame     *frame
+	requestID string
+}
+
+// frame 是一条已编码为旧协议 JSON 的消息。广播时所有接收者共享同一个 frame，
+// 每种 v2 编码只在第一个需要它的连接上编码一次。
+type frame struct {
+	data    []byte   // 旧协议的 JSON
+	message *Message // 对应的原始消息，用于写入后生成回执，可以为空
+	op      string   // v2 协议的 op，为空时由 message 的类型决定
+
+	mu      sync.Mutex
+	encoded map[Codec][]byte
+}
+
+func newFrame(data []byte, message *Message) *frame {
+	return &frame{data: data, message: message}
+}
+
+// encode 按 codec 编码，codec 为空时返回旧协议的 JSON。带有请求ID的应答只发给一个连接，不缓存。
+func (f *frame) encode(codec Codec, requestID string) ([]byte, error) {
+	if codec == nil {
+		return f.data, nil
+	}
+	op := f.op
+	if op == "" && f.message != nil {
+		op = f.message.Type.Op()
+	}
+	if requestID != "" {
+		return encodeEnvelope(codec, op, requestID, f.data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if data, ok := f.encoded[codec]; ok {
+		return data, nil
+	}
+	data, err := encodeEnvelope(codec, op, "", f.data)
+	if err != nil {
+		return nil, err
+	}
+	if f.encoded == nil {
+		f.encoded = make(map[Codec][]byte)
+	}
+	f.encoded[codec] = data
+	return data, nil
+}
+
 // Client 是 websocket 连接和 hub 之间的中间人。
 type Client struct {
 	Hub      *Hub
 	Conn     *websocket.Conn // 从 WsConnect 重命名而来，为了简洁和一致性
-	send     chan []byte
+	send     chan *outbound
 	UserId   string
 	UserName string
 	DeviceId string
+
+	UserAgent   string // 连接时的 User-Agent
+	IP          string // 连接时的客户端IP
+	ConnectTime int64  // 连接建立的时间
+	Codec       Codec  // 握手时协商的 v2 协议编码，为空表示旧协议
+
+	lastActive atomic.Int64 // 最近一次收到客户端消息的时间，用于判断用户是否空闲
+	roomDeltas atomic.Bool  // 是否接收房间增量事件，否则接收 ReloadRooms/ReloadRoomsDetail
 }
 
 func NewClient(hub *Hub, conn *websocket.Conn, userId, userName, deviceId string) *Client {
-	return &Client{
+	client := &Client{
 		Hub:      hub,
 		Conn:     conn,
-		send:     make(chan []byte, 256),
+		send:     make(chan *outbound, 256),
 		UserId:   userId,
 		UserName: userName,
 		DeviceId: deviceId,
+
+		ConnectTime: time.Now().Unix(),
+	}
+	client.lastActive.Store(client.ConnectTime)
+	return client
+}
+
+// Close 发送关闭帧后关闭连接，ReadPump 随之退出并注销客户端。
+// WriteControl 和 Close 可以与 WritePump 的写入并发调用。
+func (c *Client) Close(code int, reason string) {
+	_ = c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(global.WriteWait))
+	_ = c.Conn.Close()
+}
+
+// DecodeMessage 按连接协商的协议解析客户端发来的一帧数据，返回消息和请求ID。
+// 旧协议没有请求ID。
+func (c *Client) DecodeMessage(data []byte) (*Message, string, error) {
+	if c.Codec != nil {
+		return decodeEnvelope(c.Codec, data)
+	}
+
+	var message *Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, "", err
 	}
+	return message, "", nil
+}
+
+// Versioned 判断连接是否使用 v2 协议。
+func (c *Client) Versioned() bool {
+	return c.Codec != nil
+}
+
+// SetRoomDeltas 设置连接是否接收房间增量事件。
+func (c *Client) SetRoomDeltas(enabled bool) {
+	c.roomDeltas.Store(enabled)
+}
+
+// RoomDeltas 判断连接是否接收房间增量事件。
+func (c *Client) RoomDeltas() bool {
+	return c.roomDeltas.Load()
+}
+
+// LastActive 返回最近一次收到客户端消息的时间。
+func (c *Client) LastActive() int64 {
+	return c.lastActive.Load()
 }
 
 // readPump 将消息从 websocket 连接泵送到 hub。
@@ -52,6 +158,7 @@ func (c *Client) ReadPump(messageFunc func(client *Client, message []byte)) {
 			}
 			break // 读取错误时退出循环
 		}
+		c.lastActive.Store(time.Now().Unix())
 		// 将原始消息传递给处理函数
 		messageFunc(c, message)
 	}
@@ -61,15 +168,20 @@ func (c *Client) ReadPump(messageFunc func(client *Client, message []byte)) {
 //
 // 为每个连接启动一个运行 writePump 的 goroutine。应用程序
 // 通过从此 goroutine 执行所有写入来确保连接上最多只有一个写入器。
-func (c *Client) WritePump() {
+// 私聊消息成功写入连接后会调用 deliveredFunc，用于生成送达回执。
+func (c *Client) WritePump(deliveredFunc func(client *Client, message *Message)) {
 	ticker := time.NewTicker(global.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
 	}()
+	frameType := websocket.TextMessage
+	if c.Codec != nil {
+		frameType = c.Codec.FrameType()
+	}
 	for {
 		select {
-		case message, ok := <-c.send:
+		case out, ok := <-c.send:
 			_ = c.Conn.SetWriteDeadline(time.Now().Add(global.WriteWait))
 			if !ok {
 				// hub 关闭了 channel。
@@ -77,10 +189,19 @@ func (c *Client) WritePump() {
 				return
 			}
 
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			data, err := out.frame.encode(c.Codec, out.requestID)
+			if err != nil {
+				log.Printf("encode error: UserID: %s, %v", c.UserId, err)
+				continue
+			}
+			if err := c.Conn.WriteMessage(frameType, data); err != nil {
 				log.Printf("write error: UserID: %s, %v", c.UserId, err)
 				return
 			}
+
+			if deliveredFunc != nil && c.needsDeliveredReceipt(out.frame.message) {
+				deliveredFunc(c, out.frame.message)
+			}
 		case <-ticker.C:
 			_ = c.Conn.SetWriteDeadline(time.Now().Add(global.WriteWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -91,9 +212,50 @@ func (c *Client) WritePump() {
 	}
 }
 
+// needsDeliveredReceipt 判断写入的消息是否需要向发送者回执送达。
+// 只有别人发来的私聊消息才需要，自己其它设备的同步消息不需要。
+func (c *Client) needsDeliveredReceipt(message *Message) bool {
+	return message != nil &&
+		message.Type == MessageTypeUser &&
+		message.ID != "" &&
+		message.From != c.UserId
+}
+
 // SendMessage 是向客户端发送消息的线程安全方式。
 // 如果发送 channel 已关闭，它会从 panic 中恢复。
-func (c *Client) SendMessage(message []byte) (err error) {
+// origin 是 message 编码前的原始消息，用于写入后生成回执，可以为空。
+func (c *Client) SendMessage(message []byte, origin *Message) error {
+	return c.sendFrame(newFrame(message, origin))
+}
+
+// sendFrame 发送一个可以被多个连接共享的 frame，用于广播。
+func (c *Client) sendFrame(f *frame) error {
+	return c.enqueue(&outbound{frame: f})
+}
+
+// SendReply 发送对某个请求的应答，v2 协议的应答会带上请求ID。
+func (c *Client) SendReply(requestID string, message []byte, origin *Message) error {
+	return c.enqueue(&outbound{frame: newFrame(message, origin), requestID: requestID})
+}
+
+// SendAck 告知 v2 客户端请求已处理成功，data 为处理结果，可以为空。
+// 旧协议没有成功应答，直接忽略。
+func (c *Client) SendAck(requestID string, data any) error {
+	if c.Codec == nil {
+		return nil
+	}
+	var marshal []byte
+	if data != nil {
+		var err error
+		marshal, err = json.Marshal(data)
+		if err != nil {
+			return err
+		}
+	}
+	return c.enqueue(&outbound{frame: &frame{data: marshal, op: OpAck}, requestID: requestID})
+}
+
+func (c *Client) enqueue(out *outbound) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("recovered from panic in SendMessage for UserID %s: %v", c.UserId, r)
@@ -102,7 +264,7 @@ func (c *Client) SendMessage(message []byte) (err error) {
 	}()
 	// 非阻塞发送以防止阻塞 hub 的广播。
 	select {
-	case c.send <- message:
+	case c.send <- out:
 	default:
 		log.Printf("send channel full for UserID: %s. Message dropped.", c.UserId)
 		err = errors.New("send channel full") // 设置一个有意义的错误
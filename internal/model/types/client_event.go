@@ -1,8 +1,9 @@
 package types
 
 type RegisterEvent struct {
-	UserId string  `json:"userId"`
-	Client *Client `json:"client"`
+	UserId string        `json:"userId"`
+	Client *Client       `json:"client"`
+	Done   chan struct{} `json:"-"` // 注册完成后关闭，可以为空
 }
 
 type UnRegisterEvent struct {
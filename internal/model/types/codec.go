@@ -0,0 +1,154 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// v2 协议可以协商的编码，客户端在 Sec-WebSocket-Protocol 中按优先顺序列出。
+const (
+	ProtocolV2MsgPack  = "im.v2.msgpack"
+	ProtocolV2Protobuf = "im.v2.protobuf"
+)
+
+// Codec 负责 v2 协议的 Envelope 与 websocket 帧之间的转换。
+// Envelope.Data 在服务端内部始终是 Message 的 JSON，二进制编码把它转换为等价的结构，
+// 字段名与 JSON 相同，客户端可以用同一套模型解析三种编码。
+type Codec interface {
+	// Subprotocol 返回握手时协商的子协议名。
+	Subprotocol() string
+	// FrameType 返回写入的帧类型，websocket.TextMessage 或 websocket.BinaryMessage。
+	FrameType() int
+	Encode(envelope *Envelope) ([]byte, error)
+	Decode(data []byte) (*Envelope, error)
+}
+
+var codecs = []Codec{jsonCodec{}, newMsgPackCodec(), protobufCodec{}}
+
+// Subprotocols 返回服务端支持的所有子协议。
+func Subprotocols() []string {
+	result := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		result = append(result, c.Subprotocol())
+	}
+	return result
+}
+
+// CodecOf 返回子协议对应的编码，没有协商子协议的旧客户端返回 nil。
+func CodecOf(subprotocol string) Codec {
+	for _, c := range codecs {
+		if c.Subprotocol() == subprotocol {
+			return c
+		}
+	}
+	return nil
+}
+
+// jsonCodec 是 v2 协议的默认编码，使用文本帧。
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return ProtocolV2 }
+
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) Encode(envelope *Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonCodec) Decode(data []byte) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, InvalidEnvelopeError
+	}
+	return &envelope, nil
+}
+
+// msgPackCodec 把 Envelope 编码为 MessagePack map，键为 v、op、id、data。
+type msgPackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgPackCodec() msgPackCodec {
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	handle.WriteExt = true
+	// 解码为 map[string]any，才能再转换为 JSON
+	handle.MapType = reflect.TypeOf(map[string]any(nil))
+	return msgPackCodec{handle: handle}
+}
+
+func (msgPackCodec) Subprotocol() string { return ProtocolV2MsgPack }
+
+func (msgPackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (c msgPackCodec) Encode(envelope *Envelope) ([]byte, error) {
+	data, err := jsonTree(envelope.Data)
+	if err != nil {
+		return nil, err
+	}
+	frame := map[string]any{"v": envelope.V, "op": envelope.Op}
+	if envelope.ID != "" {
+		frame["id"] = envelope.ID
+	}
+	if data != nil {
+		frame["data"] = data
+	}
+
+	var result []byte
+	if err := codec.NewEncoderBytes(&result, c.handle).Encode(frame); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c msgPackCodec) Decode(data []byte) (*Envelope, error) {
+	var frame map[string]any
+	if err := codec.NewDecoderBytes(data, c.handle).Decode(&frame); err != nil {
+		return nil, InvalidEnvelopeError
+	}
+
+	// 借助 JSON 转换字段类型，与 JSON 编码的校验保持一致
+	marshal, err := json.Marshal(frame)
+	if err != nil {
+		return nil, InvalidEnvelopeError
+	}
+	return jsonCodec{}.Decode(marshal)
+}
+
+// jsonTree 把 JSON 解析为通用结构，整数保留为 int64，避免被转换为浮点数。
+func jsonTree(data json.RawMessage) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return convertNumbers(tree), nil
+}
+
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,417 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protobufCodec 按 envelope.proto 中的定义编码 Envelope。
+// 服务端内部的 Data 是 JSON，编码时按字段表把 JSON 的每个字段写为对应编号的 protobuf 字段，
+// 整数直接从 JSON 文本解析为 int64，解码时再按字段表还原为相同字段名的 JSON。
+type protobufCodec struct{}
+
+type protoKind int
+
+const (
+	protoString protoKind = iota
+	protoInt              // int32/int64
+	protoBool
+	protoBytes   // JSON 中是 base64 字符串
+	protoJSON    // JSON 原文，用于结构不固定的字段
+	protoMessage // 嵌套消息，字段表为 protoField.message
+)
+
+type protoField struct {
+	name     string
+	number   protowire.Number
+	kind     protoKind
+	repeated bool
+	message  []protoField
+}
+
+const (
+	protobufFieldVersion protowire.Number = 1
+	protobufFieldOp      protowire.Number = 2
+	protobufFieldID      protowire.Number = 3
+	protobufFieldMessage protowire.Number = 4
+	protobufFieldAck     protowire.Number = 5
+)
+
+var (
+	protoAckFields = []protoField{
+		{name: "messageId", number: 1, kind: protoString},
+		{name: "seq", number: 2, kind: protoInt},
+		{name: "time", number: 3, kind: protoInt},
+	}
+
+	protoFileMetaFields = []protoField{
+		{name: "name", number: 1, kind: protoString},
+		{name: "size", number: 2, kind: protoInt},
+		{name: "mime", number: 3, kind: protoString},
+		{name: "width", number: 4, kind: protoInt},
+		{name: "height", number: 5, kind: protoInt},
+		{name: "thumbnailUrl", number: 6, kind: protoString},
+		{name: "thumbnailWidth", number: 7, kind: protoInt},
+		{name: "thumbnailHeight", number: 8, kind: protoInt},
+	}
+
+	protoLinkPreviewFields = []protoField{
+		{name: "url", number: 1, kind: protoString},
+		{name: "title", number: 2, kind: protoString},
+		{name: "description", number: 3, kind: protoString},
+		{name: "image", number: 4, kind: protoString},
+		{name: "siteName", number: 5, kind: protoString},
+	}
+
+	protoPayloadFields = []protoField{
+		{name: "type", number: 1, kind: protoInt},
+		{name: "data", number: 2, kind: protoJSON},
+		{name: "file", number: 3, kind: protoBytes},
+		{name: "fileId", number: 4, kind: protoString},
+		{name: "fileMeta", number: 5, kind: protoMessage, message: protoFileMetaFields},
+		{name: "html", number: 6, kind: protoString},
+		{name: "linkPreviews", number: 7, kind: protoMessage, repeated: true, message: protoLinkPreviewFields},
+		{name: "mentions", number: 8, kind: protoString, repeated: true},
+	}
+
+	protoMessageEventFields = []protoField{
+		{name: "type", number: 1, kind: protoInt},
+		{name: "data", number: 2, kind: protoJSON},
+	}
+
+	protoReceiptFields = []protoField{
+		{name: "type", number: 1, kind: protoInt},
+		{name: "messageId", number: 2, kind: protoString},
+		{name: "conversationId", number: 3, kind: protoString},
+		{name: "seq", number: 4, kind: protoInt},
+		{name: "userId", number: 5, kind: protoString},
+		{name: "deviceId", number: 6, kind: protoString},
+		{name: "time", number: 7, kind: protoInt},
+	}
+
+	protoVoteFields = []protoField{
+		{name: "pollId", number: 1, kind: protoString},
+		{name: "optionIds", number: 2, kind: protoString, repeated: true},
+	}
+
+	protoForwardInfoFields = []protoField{
+		{name: "messageId", number: 1, kind: protoString},
+		{name: "from", number: 2, kind: protoString},
+		{name: "conversationId", number: 3, kind: protoString},
+		{name: "time", number: 4, kind: protoInt},
+	}
+
+	protoErrorInfoFields = []protoField{
+		{name: "code", number: 1, kind: protoString},
+		{name: "message", number: 2, kind: protoString},
+		{name: "type", number: 3, kind: protoInt},
+		{name: "target", number: 4, kind: protoString},
+	}
+
+	protoSubscriptionFields = []protoField{
+		{name: "add", number: 1, kind: protoString, repeated: true},
+		{name: "remove", number: 2, kind: protoString, repeated: true},
+		{name: "rooms", number: 3, kind: protoBool},
+	}
+
+	protoRoomCommandFields = []protoField{
+		{name: "roomId", number: 1, kind: protoString},
+		{name: "name", number: 2, kind: protoString},
+		{name: "password", number: 3, kind: protoString},
+	}
+
+	protoRoomSummaryFields = []protoField{
+		{name: "id", number: 1, kind: protoString},
+		{name: "name", number: 2, kind: protoString},
+		{name: "hasPassword", number: 3, kind: protoBool},
+		{name: "userId", number: 4, kind: protoString},
+		{name: "userName", number: 5, kind: protoString},
+		{name: "count", number: 6, kind: protoInt},
+		{name: "createTime", number: 7, kind: protoString},
+	}
+
+	protoMessageFields = []protoField{
+		{name: "id", number: 1, kind: protoString},
+		{name: "seq", number: 2, kind: protoInt},
+		{name: "type", number: 3, kind: protoInt},
+		{name: "payload", number: 4, kind: protoMessage, message: protoPayloadFields},
+		{name: "from", number: 5, kind: protoString},
+		{name: "to", number: 6, kind: protoString},
+		{name: "messageEvent", number: 7, kind: protoMessage, message: protoMessageEventFields},
+		{name: "receipt", number: 8, kind: protoMessage, message: protoReceiptFields},
+		{name: "vote", number: 9, kind: protoMessage, message: protoVoteFields},
+		{name: "forwarded", number: 10, kind: protoMessage, message: protoForwardInfoFields},
+		{name: "error", number: 11, kind: protoMessage, message: protoErrorInfoFields},
+		{name: "subscribe", number: 12, kind: protoMessage, message: protoSubscriptionFields},
+		{name: "roomCommand", number: 13, kind: protoMessage, message: protoRoomCommandFields},
+		{name: "room", number: 14, kind: protoMessage, message: protoRoomSummaryFields},
+		{name: "time", number: 15, kind: protoInt},
+		{name: "ttl", number: 16, kind: protoInt},
+		{name: "expireAt", number: 17, kind: protoInt},
+	}
+)
+
+var protoFieldTypeError = errors.New("protobuf field type mismatch")
+
+func (protobufCodec) Subprotocol() string { return ProtocolV2Protobuf }
+
+func (protobufCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (protobufCodec) Encode(envelope *Envelope) ([]byte, error) {
+	var result []byte
+	result = protowire.AppendTag(result, protobufFieldVersion, protowire.VarintType)
+	result = protowire.AppendVarint(result, uint64(envelope.V))
+	result = protowire.AppendTag(result, protobufFieldOp, protowire.BytesType)
+	result = protowire.AppendString(result, envelope.Op)
+	if envelope.ID != "" {
+		result = protowire.AppendTag(result, protobufFieldID, protowire.BytesType)
+		result = protowire.AppendString(result, envelope.ID)
+	}
+	if len(envelope.Data) == 0 {
+		return result, nil
+	}
+
+	// 保留 json.Number，整数不经过浮点数转换
+	decoder := json.NewDecoder(bytes.NewReader(envelope.Data))
+	decoder.UseNumber()
+	var data map[string]any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	number, fields := protobufFieldMessage, protoMessageFields
+	if envelope.Op == OpAck {
+		number, fields = protobufFieldAck, protoAckFields
+	}
+	message, err := appendProtoMessage(nil, fields, data)
+	if err != nil {
+		return nil, err
+	}
+	result = protowire.AppendTag(result, number, protowire.BytesType)
+	return protowire.AppendBytes(result, message), nil
+}
+
+func (protobufCodec) Decode(data []byte) (*Envelope, error) {
+	envelope := &Envelope{}
+	err := consumeProtoFields(data, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+		switch {
+		case number == protobufFieldVersion && wireType == protowire.VarintType:
+			envelope.V = int(varint)
+		case number == protobufFieldOp && wireType == protowire.BytesType:
+			envelope.Op = string(value)
+		case number == protobufFieldID && wireType == protowire.BytesType:
+			envelope.ID = string(value)
+		case (number == protobufFieldMessage || number == protobufFieldAck) && wireType == protowire.BytesType:
+			fields := protoMessageFields
+			if number == protobufFieldAck {
+				fields = protoAckFields
+			}
+			message, err := readProtoMessage(fields, value)
+			if err != nil {
+				return err
+			}
+			if envelope.Data, err = json.Marshal(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, InvalidEnvelopeError
+	}
+	return envelope, nil
+}
+
+// appendProtoMessage 按字段表把 JSON 对象编码为 protobuf 消息，字段表之外的字段和 null 被忽略。
+func appendProtoMessage(result []byte, fields []protoField, object map[string]any) ([]byte, error) {
+	for _, field := range fields {
+		value, ok := object[field.name]
+		if !ok || value == nil {
+			continue
+		}
+		if !field.repeated {
+			var err error
+			if result, err = appendProtoField(result, field, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", protoFieldTypeError, field.name)
+		}
+		for _, item := range items {
+			var err error
+			if result, err = appendProtoField(result, field, item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func appendProtoField(result []byte, field protoField, value any) ([]byte, error) {
+	switch field.kind {
+	case protoString:
+		s, ok := value.(string)
+		if !ok {
+			break
+		}
+		result = protowire.AppendTag(result, field.number, protowire.BytesType)
+		return protowire.AppendString(result, s), nil
+	case protoInt:
+		n, ok := value.(json.Number)
+		if !ok {
+			break
+		}
+		i, err := n.Int64()
+		if err != nil {
+			break
+		}
+		result = protowire.AppendTag(result, field.number, protowire.VarintType)
+		return protowire.AppendVarint(result, uint64(i)), nil
+	case protoBool:
+		b, ok := value.(bool)
+		if !ok {
+			break
+		}
+		result = protowire.AppendTag(result, field.number, protowire.VarintType)
+		return protowire.AppendVarint(result, protowire.EncodeBool(b)), nil
+	case protoBytes:
+		s, ok := value.(string)
+		if !ok {
+			break
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			break
+		}
+		result = protowire.AppendTag(result, field.number, protowire.BytesType)
+		return protowire.AppendBytes(result, b), nil
+	case protoJSON:
+		marshal, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		result = protowire.AppendTag(result, field.number, protowire.BytesType)
+		return protowire.AppendBytes(result, marshal), nil
+	case protoMessage:
+		object, ok := value.(map[string]any)
+		if !ok {
+			break
+		}
+		message, err := appendProtoMessage(nil, field.message, object)
+		if err != nil {
+			return nil, err
+		}
+		result = protowire.AppendTag(result, field.number, protowire.BytesType)
+		return protowire.AppendBytes(result, message), nil
+	}
+	return nil, fmt.Errorf("%w: %s", protoFieldTypeError, field.name)
+}
+
+// readProtoMessage 按字段表把 protobuf 消息解码为 JSON 对象，不认识的字段被跳过。
+func readProtoMessage(fields []protoField, data []byte) (map[string]any, error) {
+	object := make(map[string]any)
+	err := consumeProtoFields(data, func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error {
+		field, ok := findProtoField(fields, number)
+		if !ok {
+			return nil
+		}
+		item, err := readProtoField(field, wireType, value, varint)
+		if err != nil {
+			return err
+		}
+		if field.repeated {
+			items, _ := object[field.name].([]any)
+			object[field.name] = append(items, item)
+		} else {
+			object[field.name] = item
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func readProtoField(field protoField, wireType protowire.Type, value []byte, varint uint64) (any, error) {
+	if field.kind == protoInt || field.kind == protoBool {
+		if wireType != protowire.VarintType {
+			return nil, protoFieldTypeError
+		}
+		if field.kind == protoBool {
+			return protowire.DecodeBool(varint), nil
+		}
+		return int64(varint), nil
+	}
+
+	if wireType != protowire.BytesType {
+		return nil, protoFieldTypeError
+	}
+	switch field.kind {
+	case protoString:
+		return string(value), nil
+	case protoBytes:
+		// []byte 转换为 JSON 时是 base64 字符串，与 JSON 编码一致
+		return append([]byte(nil), value...), nil
+	case protoJSON:
+		if !json.Valid(value) {
+			return nil, protoFieldTypeError
+		}
+		return json.RawMessage(append([]byte(nil), value...)), nil
+	default:
+		return readProtoMessage(field.message, value)
+	}
+}
+
+func findProtoField(fields []protoField, number protowire.Number) (protoField, bool) {
+	for _, field := range fields {
+		if field.number == number {
+			return field, true
+		}
+	}
+	return protoField{}, false
+}
+
+// consumeProtoFields 依次读取 protobuf 消息的字段，varint 字段的值在 varint 中，其他字段的值在 value 中。
+func consumeProtoFields(data []byte, handleField func(number protowire.Number, wireType protowire.Type, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		var varint uint64
+		switch wireType {
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			// 跳过其他类型的字段，便于以后扩展
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if wireType != protowire.VarintType && wireType != protowire.BytesType {
+			continue
+		}
+		if err := handleField(number, wireType, value, varint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	// 超过 2^53 的整数，经过浮点数转换后会丢失精度
+	const seq int64 = 1<<53 + 1
+	const timestamp int64 = 1760000000123
+
+	eventData := json.RawMessage(`{"conversationId":"room:r1","seq":9007199254740993,"devices":[{"id":"d1","readSeq":42}],"ratio":0.5,"muted":true,"note":null}`)
+	message := &Message{
+		ID:           "m1",
+		Seq:          seq,
+		Type:         MessageTypeSystem,
+		From:         "u1",
+		To:           "r1",
+		Payload:      &Payload{Content: json.RawMessage(`"你好"`)},
+		MessageEvent: NewMessageEventPayload(ReadStateSync, eventData),
+		Timestamp:    timestamp,
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, codec := range codecs {
+		t.Run(codec.Subprotocol(), func(t *testing.T) {
+			frame, err := encodeEnvelope(codec, message.Type.Op(), "req-1", data)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			got, requestID, err := decodeEnvelope(codec, frame)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if requestID != "req-1" {
+				t.Errorf("request id = %q, want %q", requestID, "req-1")
+			}
+			if got.Seq != seq || got.Timestamp != timestamp {
+				t.Errorf("seq/time = %d/%d, want %d/%d", got.Seq, got.Timestamp, seq, timestamp)
+			}
+			if got.ID != message.ID || got.Type != message.Type || got.From != message.From || got.To != message.To {
+				t.Errorf("message = %+v, want %+v", got, message)
+			}
+			if got.Payload == nil || string(got.Payload.Content) != string(message.Payload.Content) {
+				t.Errorf("payload = %+v, want %+v", got.Payload, message.Payload)
+			}
+			if got.MessageEvent == nil || got.MessageEvent.Type != ReadStateSync {
+				t.Fatalf("message event = %+v", got.MessageEvent)
+			}
+
+			want, _ := jsonTree(eventData)
+			have, err := jsonTree(got.MessageEvent.Data)
+			if err != nil {
+				t.Fatalf("event data: %v", err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("event data = %s, want %s", got.MessageEvent.Data, eventData)
+			}
+		})
+	}
+}
+
+func TestCodecEmptyData(t *testing.T) {
+	for _, codec := range codecs {
+		t.Run(codec.Subprotocol(), func(t *testing.T) {
+			frame, err := encodeEnvelope(codec, OpAck, "req-2", nil)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			envelope, err := codec.Decode(frame)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if envelope.V != EnvelopeVersion || envelope.Op != OpAck || envelope.ID != "req-2" || len(envelope.Data) != 0 {
+				t.Errorf("envelope = %+v", envelope)
+			}
+		})
+	}
+}
+
+func TestCodecDecodeInvalid(t *testing.T) {
+	for _, codec := range codecs {
+		t.Run(codec.Subprotocol(), func(t *testing.T) {
+			if _, err := codec.Decode([]byte{0xc1}); err == nil {
+				t.Error("decode invalid frame: want error")
+			}
+		})
+	}
+}
+
+func TestCodecRoundTripAllFields(t *testing.T) {
+	cases := []struct {
+		name string
+		op   string
+		data string
+	}{
+		{
+			name: "message",
+			op:   MessageTypeRoom.Op(),
+			data: `{"id":"m1","seq":9007199254740993,"type":2,"from":"u1","to":"r1","time":1760000000123,"ttl":-1,"expireAt":1760000000999,
+				"payload":{"type":2,"data":{"text":"hi","n":9007199254740993},"file":"AAEC/w==","fileId":"f1","html":"<p>hi</p>","mentions":["u2","u3"],
+					"fileMeta":{"name":"a.png","size":1048576,"mime":"image/png","width":64,"height":32,"thumbnailUrl":"/t","thumbnailWidth":16,"thumbnailHeight":8},
+					"linkPreviews":[{"url":"https://a.example","title":"A"},{"url":"https://b.example","siteName":"B"}]},
+				"messageEvent":{"type":3,"data":[1,"two",{"three":3.5}]},
+				"receipt":{"type":1,"messageId":"m0","conversationId":"room:r1","seq":7,"userId":"u2","deviceId":"d2","time":1760000000},
+				"vote":{"pollId":"p1","optionIds":["o1","o2"]},
+				"forwarded":{"messageId":"m0","from":"u3","conversationId":"dm:u1:u3","time":1750000000},
+				"error":{"code":"blocked","message":"blocked","type":3,"target":"u4"},
+				"subscribe":{"add":["u5"],"remove":["u6"],"rooms":false},
+				"roomCommand":{"roomId":"r1","name":"lobby","password":"secret"},
+				"room":{"id":"r1","name":"lobby","hasPassword":true,"userId":"u1","userName":"alice","count":0,"createTime":"2026-10-19 12:00:00"}}`,
+		},
+		{
+			name: "ack",
+			op:   OpAck,
+			data: `{"messageId":"m1","seq":9007199254740993,"time":1760000000123}`,
+		},
+	}
+
+	for _, codec := range codecs {
+		for _, tc := range cases {
+			t.Run(codec.Subprotocol()+"/"+tc.name, func(t *testing.T) {
+				frame, err := encodeEnvelope(codec, tc.op, "req-3", []byte(tc.data))
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+				envelope, err := codec.Decode(frame)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if envelope.Op != tc.op || envelope.ID != "req-3" {
+					t.Errorf("op/id = %q/%q, want %q/%q", envelope.Op, envelope.ID, tc.op, "req-3")
+				}
+
+				want, _ := jsonTree(json.RawMessage(tc.data))
+				have, err := jsonTree(envelope.Data)
+				if err != nil {
+					t.Fatalf("data: %v", err)
+				}
+				if !reflect.DeepEqual(have, want) {
+					t.Errorf("data = %s, want %s", envelope.Data, tc.data)
+				}
+			})
+		}
+	}
+}
+
+// TestProtobufSchemaCoversMessage 保证 Message 新增的 JSON 字段也加到了 protobuf 的字段表中。
+func TestProtobufSchemaCoversMessage(t *testing.T) {
+	var check func(path string, typ reflect.Type, fields []protoField)
+	check = func(path string, typ reflect.Type, fields []protoField) {
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			field, ok := findProtoFieldByName(fields, name)
+			if !ok {
+				t.Errorf("%s.%s is missing from the protobuf schema", path, name)
+				continue
+			}
+
+			fieldType := typ.Field(i).Type
+			if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8 {
+				if !field.repeated {
+					t.Errorf("%s.%s should be repeated", path, name)
+				}
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if field.kind == protoMessage {
+				check(path+"."+name, fieldType, field.message)
+			}
+		}
+	}
+	check("Message", reflect.TypeOf(Message{}), protoMessageFields)
+	check("Ack", reflect.TypeOf(Ack{}), protoAckFields)
+}
+
+func findProtoFieldByName(fields []protoField, name string) (protoField, bool) {
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	return protoField{}, false
+}
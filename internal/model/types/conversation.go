@@ -0,0 +1,62 @@
+package types
+
+import (
+	"strings"
+)
+
+// 会话ID前缀，用于区分房间和私聊
+const (
+	ConversationPrefixRoom  = "room:"
+	ConversationPrefixUser  = "dm:"
+	ConversationPrefixGroup = "group:"
+)
+
+// RoomConversationID 返回房间对应的会话ID。
+func RoomConversationID(roomID string) string {
+	return ConversationPrefixRoom + roomID
+}
+
+// DirectConversationID 返回两个用户之间私聊的会话ID，与参数顺序无关。
+func DirectConversationID(userA, userB string) string {
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+	return ConversationPrefixUser + userA + ":" + userB
+}
+
+// GroupConversationID 返回群组对应的会话ID。
+func GroupConversationID(groupID string) string {
+	return ConversationPrefixGroup + groupID
+}
+
+// ConversationIDOf 返回消息所属的会话ID，非会话消息返回空字符串。
+func ConversationIDOf(msg *Message) string {
+	switch msg.Type {
+	case MessageTypeRoom:
+		return RoomConversationID(msg.To)
+	case MessageTypeUser:
+		return DirectConversationID(msg.From, msg.To)
+	case MessageTypeGroup:
+		return GroupConversationID(msg.To)
+	}
+	return ""
+}
+
+// ConversationPeers 解析私聊会话ID，返回两个参与者。
+func ConversationPeers(conversationID string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(conversationID, ConversationPrefixUser)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+// ConversationRoomID 解析房间会话ID，返回房间ID。
+func ConversationRoomID(conversationID string) (string, bool) {
+	return strings.CutPrefix(conversationID, ConversationPrefixRoom)
+}
+
+// ConversationGroupID 解析群组会话ID，返回群组ID。
+func ConversationGroupID(conversationID string) (string, bool) {
+	return strings.CutPrefix(conversationID, ConversationPrefixGroup)
+}
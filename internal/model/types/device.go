@@ -0,0 +1,45 @@
+package types
+
+// DeviceInfo 是用户一个在线设备的连接信息。
+type DeviceInfo struct {
+	DeviceID    string `json:"deviceId"`
+	UserAgent   string `json:"userAgent"`
+	IP          string `json:"ip"`
+	ConnectTime int64  `json:"connectTime"`
+	LastActive  int64  `json:"lastActive"`
+}
+
+// UserDevices 返回用户所有在线设备，同一设备有多个连接时每个连接单独列出。
+func (h *Hub) UserDevices(userID string) []*DeviceInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	devices := make([]*DeviceInfo, 0, len(h.Users[userID]))
+	for client := range h.Users[userID] {
+		devices = append(devices, &DeviceInfo{
+			DeviceID:    client.DeviceId,
+			UserAgent:   client.UserAgent,
+			IP:          client.IP,
+			ConnectTime: client.ConnectTime,
+			LastActive:  client.LastActive(),
+		})
+	}
+	return devices
+}
+
+// CloseDevice 关闭用户某个设备的所有连接，返回关闭的连接数。
+func (h *Hub) CloseDevice(userID, deviceID string, code int, reason string) int {
+	h.mu.RLock()
+	var clients []*Client
+	for client := range h.Users[userID] {
+		if client.DeviceId == deviceID {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.Close(code, reason)
+	}
+	return len(clients)
+}
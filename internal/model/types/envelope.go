@@ -0,0 +1,113 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ProtocolV2 是握手时通过 Sec-WebSocket-Protocol 协商的协议版本，使用 JSON 编码，
+// 二进制编码见 codec.go。没有协商子协议的旧客户端继续直接收发 Message JSON。
+const (
+	ProtocolV2 = "im.v2"
+
+	EnvelopeVersion = 2
+)
+
+// OpAck 是请求处理成功的应答，只发给 v2 客户端，ID 为请求的 ID。
+const OpAck = "ack"
+
+var (
+	InvalidEnvelopeError      = errors.New("invalid envelope")
+	UnsupportedVersionError   = errors.New("unsupported protocol version")
+	UnsupportedOperationError = errors.New("unsupported operation")
+)
+
+// Envelope 是 v2 协议的一帧数据。
+// Op 用字符串表示消息类型，Data 与旧协议的 Message JSON 相同；
+// 客户端请求携带 ID 时，服务端的应答（ack 或 error）会带回相同的 ID。
+type Envelope struct {
+	V    int             `json:"v"`
+	Op   string          `json:"op"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Ack 是发送消息成功后的应答内容。
+type Ack struct {
+	MessageID string `json:"messageId,omitempty"`
+	Seq       int64  `json:"seq,omitempty"`
+	Timestamp int64  `json:"time,omitempty"`
+}
+
+var messageTypeOps = map[MessageType]string{
+	MessageTypeJoin:      "join",
+	MessageTypeLeave:     "leave",
+	MessageTypeRoom:      "room",
+	MessageTypeUser:      "user",
+	MessageTypeGlobal:    "global",
+	MessageTypeSystem:    "system",
+	MessageTypeJoinRoom:  "room.join",
+	MessageTypeLeaveRoom: "room.leave",
+	MessageTypeReceipt:   "receipt",
+	MessageTypeUpdate:    "update",
+	MessageTypeVote:      "vote",
+	MessageTypeGroup:     "group",
+	MessageTypeError:     "error",
+	MessageTypeSubscribe: "subscribe",
+
+	MessageTypeCreateRoom: "room.create",
+}
+
+var opMessageTypes = func() map[string]MessageType {
+	result := make(map[string]MessageType, len(messageTypeOps))
+	for t, op := range messageTypeOps {
+		result[op] = t
+	}
+	return result
+}()
+
+// Op 返回消息类型在 v2 协议中的名称。
+func (t MessageType) Op() string {
+	return messageTypeOps[t]
+}
+
+// MessageTypeOfOp 返回 v2 协议中的名称对应的消息类型。
+func MessageTypeOfOp(op string) (MessageType, bool) {
+	t, ok := opMessageTypes[op]
+	return t, ok
+}
+
+// encodeEnvelope 用 codec 把一帧旧协议的数据包装为 v2 协议的数据。
+func encodeEnvelope(codec Codec, op, id string, data []byte) ([]byte, error) {
+	return codec.Encode(&Envelope{
+		V:    EnvelopeVersion,
+		Op:   op,
+		ID:   id,
+		Data: data,
+	})
+}
+
+// decodeEnvelope 用 codec 解析 v2 协议的一帧数据，返回其中的消息和请求ID。
+// 解析失败时仍尽量返回请求ID，以便把错误应答给对应的请求。
+func decodeEnvelope(codec Codec, data []byte) (*Message, string, error) {
+	envelope, err := codec.Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if envelope.V != EnvelopeVersion {
+		return nil, envelope.ID, UnsupportedVersionError
+	}
+	messageType, ok := MessageTypeOfOp(envelope.Op)
+	if !ok {
+		return nil, envelope.ID, UnsupportedOperationError
+	}
+
+	message := &Message{}
+	if len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, message); err != nil {
+			return nil, envelope.ID, InvalidEnvelopeError
+		}
+	}
+	message.Type = messageType
+	return message, envelope.ID, nil
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+)
+
+// Group 是私有的多人会话。与房间不同，成员关系不依赖连接，
+// 成员离线后依然在群组中，只能通过添加/移除操作改变。
+type Group struct {
+	ID         string
+	Name       string
+	OwnerID    string
+	Members    map[string]bool // 成员用户ID
+	CreateTime time.Time
+}
+
+func NewGroup(id, name, ownerID string, members []string) *Group {
+	group := &Group{
+		ID:         id,
+		Name:       name,
+		OwnerID:    ownerID,
+		Members:    map[string]bool{ownerID: true},
+		CreateTime: time.Now(),
+	}
+	for _, member := range members {
+		group.Members[member] = true
+	}
+	return group
+}
+
+// snapshot 返回群组的副本，调用者可以在锁外安全读取。
+func (g *Group) snapshot() *Group {
+	copied := *g
+	copied.Members = make(map[string]bool, len(g.Members))
+	for member := range g.Members {
+		copied.Members[member] = true
+	}
+	return &copied
+}
+
+// MemberIDs 返回成员ID列表。
+func (g *Group) MemberIDs() []string {
+	members := make([]string, 0, len(g.Members))
+	for member := range g.Members {
+		members = append(members, member)
+	}
+	return members
+}
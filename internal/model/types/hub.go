@@ -21,6 +21,7 @@ type Hub struct {
 
 	Rooms     map[string]*Room           // 房间ID, 房间
 	UserRooms map[string]map[string]bool // 用户ID, 房间IDs
+	Groups    map[string]*Group          // 群组ID, 群组
 
 	Broadcast  chan *Message         // 广播
 	Register   chan *RegisterEvent   // 注册链接
@@ -29,6 +30,22 @@ type Hub struct {
 	CreateRoom chan *CreateRoomEvent // 创建房间
 	JoinRoom   chan *JoinRoomEvent   // 加入房间
 	UnjoinRoom chan *UnJoinRoomEvent // 退出房间
+
+	// deliveryGuard 在投递私聊前检查发送者是否被允许给接收者发消息，为空时不检查。
+	deliveryGuard func(from, to string) bool
+	// blockGuard 判断 userID 是否屏蔽了 targetID，投递群组消息时跳过屏蔽了发送者的成员，为空时不检查。
+	blockGuard func(userID, targetID string) bool
+	// listeners 在设备连接和断开后被通知，上线、离线通知由它们负责发出。
+	listeners []ConnectionListener
+	// roomListVersion 是房间列表的版本，每次有房间创建、删除或人数变化时增加
+	roomListVersion int64
+}
+
+// ConnectionListener 接收设备连接和断开的通知，在 hub 的锁之外异步调用，
+// 实现者需要自己从 hub 读取最新状态，而不是依赖通知的先后顺序。
+type ConnectionListener interface {
+	ClientConnected(client *Client)
+	ClientDisconnected(client *Client)
 }
 
 func NewHub() *Hub {
@@ -38,6 +55,7 @@ func NewHub() *Hub {
 		UserInfos:  make(map[string]map[*UserInfo]bool),
 		Rooms:      make(map[string]*Room),
 		UserRooms:  make(map[string]map[string]bool),
+		Groups:     make(map[string]*Group),
 		Broadcast:  make(chan *Message),
 		CreateRoom: make(chan *CreateRoomEvent),
 		Register:   make(chan *RegisterEvent),
@@ -92,18 +110,13 @@ func registerClient(h *Hub, event *RegisterEvent) {
 	}
 	h.Users[event.UserId][event.Client] = true
 
-	// 通知用户上线了刷新 /users接口
-	go func() {
-		marshal, _ := json.Marshal(map[string]string{
-			"userId":  event.UserId,
-			"message": "online",
-		})
-
-		h.Broadcast <- NewMessageEvent(
-			MessageTypeGlobal,
-			NewMessageEventPayload(ReloadUsers, marshal),
-		)
-	}()
+	for _, listener := range h.listeners {
+		go listener.ClientConnected(event.Client)
+	}
+
+	if event.Done != nil {
+		close(event.Done)
+	}
 }
 
 func unRegisterClient(h *Hub, event *UnRegisterEvent) {
@@ -130,6 +143,8 @@ func unRegisterClient(h *Hub, event *UnRegisterEvent) {
 		for roomId, room := range h.Rooms {
 			if _, ok := room.Clients[event.Client]; ok {
 				delete(room.Clients, event.Client)
+				h.roomMembersChangedNoLock(RoomMemberLeft, room, event.Client)
+				h.removeUserRoomNoLock(room, event.UserId)
 				// 如果客户端离开后房间为空，则删除房间
 				if len(room.Clients) == 0 {
 					delete(h.Rooms, roomId)
@@ -140,20 +155,16 @@ func unRegisterClient(h *Hub, event *UnRegisterEvent) {
 							delete(h.UserRooms, room.UserID)
 						}
 					}
+					h.roomListChangedNoLock(RoomDeleted, roomId, nil)
+				} else {
+					h.roomListChangedNoLock(RoomUpdated, roomId, room)
 				}
 			}
 		}
 
-		go func() {
-			marshal, _ := json.Marshal(map[string]string{
-				"userId":  event.UserId,
-				"message": "offline",
-			})
-			h.Broadcast <- NewMessageEvent(
-				MessageTypeGlobal,
-				NewMessageEventPayload(ReloadUsers, marshal),
-			)
-		}()
+		for _, listener := range h.listeners {
+			go listener.ClientDisconnected(event.Client)
+		}
 
 		log.Printf("unregistered client: %s", event.UserId)
 	}
@@ -185,21 +196,38 @@ func broadcast(h *Hub, msg *Message) {
 				targetClients = append(targetClients, client)
 			}
 		}
-	} else if msg.Type == MessageTypeUser {
+	} else if msg.Type == MessageTypeUser || msg.Type == MessageTypeReceipt || msg.Type == MessageTypeSystem {
+		// 私聊是唯一知道用户ID就能发送的消息，投递前按接收者的屏蔽列表和隐私设置再检查一次
+		if msg.Type == MessageTypeUser && h.deliveryGuard != nil && !h.deliveryGuard(msg.From, msg.To) {
+			log.Printf("broadcast dm rejected: %s -> %s", msg.From, msg.To)
+			return
+		}
 		if clients, ok := h.Users[msg.To]; ok {
 			for client := range clients {
 				targetClients = append(targetClients, client)
 			}
 		}
+	} else if msg.Type == MessageTypeGroup {
+		// 群组成员可能屏蔽了发送者，被屏蔽的人发的群消息不投递给屏蔽者
+		for _, client := range h.conversationClientsNoLock(GroupConversationID(msg.To)) {
+			if h.blockGuard != nil && client.UserId != msg.From && h.blockGuard(client.UserId, msg.From) {
+				continue
+			}
+			targetClients = append(targetClients, client)
+		}
+	} else if msg.Type == MessageTypeUpdate {
+		targetClients = h.conversationClientsNoLock(msg.To)
 	}
 
 	// 现在在锁范围之外使用收集到的 targetClients 发送消息
 	// 注意：SendMessage 是非阻塞的，并且会处理自己的 panic，因此在锁外调用是安全的。
+	// 所有接收者共享一个 frame，每种编码只编码一次
+	f := newFrame(messageMarshal, msg)
 	for _, client := range targetClients {
-		err := client.SendMessage(messageMarshal)
-		if err != nil {
-			log.Printf("broadcast SendMessage error: %v", err)
-			return
+		// 单个连接发送失败（缓冲区已满或已关闭）不影响其他接收者
+		if err := client.sendFrame(f); err != nil {
+			log.Printf("broadcast SendMessage error: UserID: %s, %v", client.UserId, err)
+			continue
 		}
 	}
 }
@@ -208,22 +236,22 @@ func createRoomFunc(h *Hub, event *CreateRoomEvent) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	log.Printf("create room: %s", event.Room.ID)
+	h.createRoomNoLock(event.Room)
+}
+
+func (h *Hub) createRoomNoLock(room *Room) {
+	log.Printf("create room: %s", room.ID)
 
-	h.Rooms[event.Room.ID] = event.Room
+	h.Rooms[room.ID] = room
 
 	// 添加到用户的房间映射中
-	if _, ok := h.UserRooms[event.Room.UserID]; !ok {
-		h.UserRooms[event.Room.UserID] = make(map[string]bool)
+	if _, ok := h.UserRooms[room.UserID]; !ok {
+		h.UserRooms[room.UserID] = make(map[string]bool)
 	}
-	h.UserRooms[event.Room.UserID][event.Room.ID] = true
+	h.UserRooms[room.UserID][room.ID] = true
 
-	go func() {
-		h.Broadcast <- NewMessageEvent(
-			MessageTypeGlobal,
-			NewMessageEventPayload(ReloadRooms, nil),
-		)
-	}()
+	h.roomListChangedNoLock(RoomCreated, room.ID, room)
+	h.sendLegacyRoomEventNoLock(NewMessageEventPayload(ReloadRooms, nil))
 }
 
 func joinRoom(h *Hub, event *JoinRoomEvent) {
@@ -238,45 +266,47 @@ func joinRoom(h *Hub, event *JoinRoomEvent) {
 		return
 	}
 
-	// 调用 NoLock 版本，因为我们已经持有了写锁
-	currentClient, err := h.findClientNoLock(event.UserID, event.DeviceID)
-	if errors.Is(err, ClientNotFindError) {
-		log.Printf("client not exist: %s", event.UserID)
-		return
+	// 事件带有连接时直接使用，否则按设备查找，调用 NoLock 版本，因为我们已经持有了写锁
+	currentClient := event.Client
+	if currentClient == nil {
+		var err error
+		currentClient, err = h.findClientNoLock(event.UserID, event.DeviceID)
+		if errors.Is(err, ClientNotFindError) {
+			log.Printf("client not exist: %s", event.UserID)
+			return
+		}
 	}
 
+	h.joinRoomNoLock(room, currentClient)
+}
+
+// joinRoomNoLock 把连接加入房间，调用者需持有 hub 写锁。
+func (h *Hub) joinRoomNoLock(room *Room, currentClient *Client) {
 	// 将客户端添加到房间的客户端集合中
 	// 确保 room.Clients 已初始化。如果 NewRoom 被正确调用，它应该是初始化的。
 	// 但是，检查更安全。Room 结构体有 Clients map。
 	if room.Clients == nil {
 		room.Clients = make(map[*Client]bool)
 	}
-	room.Clients[currentClient] = true
+	if !room.Clients[currentClient] {
+		room.Clients[currentClient] = true
+		h.roomMembersChangedNoLock(RoomMemberJoined, room, currentClient)
+		h.roomListChangedNoLock(RoomUpdated, room.ID, room)
+	}
 
 	// 将房间添加到用户的房间映射中
-	if _, ok := h.UserRooms[event.UserID]; !ok {
-		h.UserRooms[event.UserID] = make(map[string]bool)
+	if _, ok := h.UserRooms[currentClient.UserId]; !ok {
+		h.UserRooms[currentClient.UserId] = make(map[string]bool)
 	}
-	h.UserRooms[event.UserID][event.RoomID] = true
+	h.UserRooms[currentClient.UserId][room.ID] = true
 
-	// Broadcast ReloadRoomsDetail to all clients
-	roomIDBytes, err := json.Marshal(event.RoomID)
+	// Send ReloadRoomsDetail to legacy clients
+	roomIDBytes, err := json.Marshal(room.ID)
 	if err != nil {
 		log.Printf("Error marshalling room ID for ReloadRoomsDetail: %v", err)
 	} else {
-		go func() {
-			h.Broadcast <- NewMessageEvent(
-				MessageTypeGlobal, // This should be MessageTypeGlobal to be caught by the frontend as a global event
-				NewMessageEventPayload(ReloadRoomsDetail, json.RawMessage(roomIDBytes)),
-			)
-		}()
-
-		go func() {
-			h.Broadcast <- NewMessageEvent(
-				MessageTypeGlobal,
-				NewMessageEventPayload(ReloadRooms, nil),
-			)
-		}()
+		h.sendLegacyRoomEventNoLock(NewMessageEventPayload(ReloadRoomsDetail, json.RawMessage(roomIDBytes)))
+		h.sendLegacyRoomEventNoLock(NewMessageEventPayload(ReloadRooms, nil))
 	}
 }
 
@@ -298,37 +328,133 @@ func unjoinRoom(h *Hub, event *UnJoinRoomEvent) {
 		return
 	}
 
-	// Remove client from room's clients
-	if _, ok := room.Clients[currentClient]; ok {
-		delete(room.Clients, currentClient)
+	if err := h.leaveRoomNoLock(room, currentClient); err != nil {
+		log.Printf("client not in room: %s, %s", event.UserID, event.RoomID)
 	}
+}
 
-	// Remove room from user's rooms
-	if userRoomSet, ok := h.UserRooms[event.UserID]; ok {
-		delete(userRoomSet, event.RoomID)
-		if len(userRoomSet) == 0 {
-			delete(h.UserRooms, event.UserID)
-		}
+// leaveRoomNoLock 把连接移出房间，房间没有连接后被删除，调用者需持有 hub 写锁。
+// 连接不在房间中时不做任何修改，返回 NotRoomMemberError。
+func (h *Hub) leaveRoomNoLock(room *Room, currentClient *Client) error {
+	if !room.Clients[currentClient] {
+		return NotRoomMemberError
 	}
+	delete(room.Clients, currentClient)
+	h.roomMembersChangedNoLock(RoomMemberLeft, room, currentClient)
+	h.removeUserRoomNoLock(room, currentClient.UserId)
 
 	// If the room becomes empty, remove the room itself
 	if len(room.Clients) == 0 {
-		delete(h.Rooms, event.RoomID)
-		log.Printf("room %s is now empty and removed", event.RoomID)
+		delete(h.Rooms, room.ID)
+		log.Printf("room %s is now empty and removed", room.ID)
+		h.roomListChangedNoLock(RoomDeleted, room.ID, nil)
+	} else {
+		h.roomListChangedNoLock(RoomUpdated, room.ID, room)
 	}
 
-	// Broadcast ReloadRoomsDetail to all clients
-	roomIDBytes, err := json.Marshal(event.RoomID)
+	// Send ReloadRoomsDetail to legacy clients
+	roomIDBytes, err := json.Marshal(room.ID)
 	if err != nil {
 		log.Printf("Error marshalling room ID for ReloadRoomsDetail: %v", err)
 	} else {
-		go func() {
-			h.Broadcast <- NewMessageEvent(
-				MessageTypeGlobal,
-				NewMessageEventPayload(ReloadRoomsDetail, json.RawMessage(roomIDBytes)),
-			)
-		}()
+		h.sendLegacyRoomEventNoLock(NewMessageEventPayload(ReloadRoomsDetail, json.RawMessage(roomIDBytes)))
 	}
+	return nil
+}
+
+// removeUserRoomNoLock 在用户的所有设备都离开房间后，把房间从用户的房间映射中删除，调用者需持有 hub 写锁。
+func (h *Hub) removeUserRoomNoLock(room *Room, userID string) {
+	for client := range room.Clients {
+		if client.UserId == userID {
+			return
+		}
+	}
+	if userRoomSet, ok := h.UserRooms[userID]; ok {
+		delete(userRoomSet, room.ID)
+		if len(userRoomSet) == 0 {
+			delete(h.UserRooms, userID)
+		}
+	}
+}
+
+// AddConnectionListener 注册设备连接和断开的监听者，需要在有设备连接之前调用。
+func (h *Hub) AddConnectionListener(listener ConnectionListener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.listeners = append(h.listeners, listener)
+}
+
+// AllClients 返回所有在线设备。
+func (h *Hub) AllClients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// UserActivity 返回用户所有在线设备中最近一次活动的时间，用户没有在线设备时 online 为 false。
+func (h *Hub) UserActivity(userID string) (lastActive int64, online bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.Users[userID] {
+		online = true
+		if active := client.LastActive(); active > lastActive {
+			lastActive = active
+		}
+	}
+	return lastActive, online
+}
+
+// SetDeliveryGuard 设置私聊投递检查。
+func (h *Hub) SetDeliveryGuard(guard func(from, to string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deliveryGuard = guard
+}
+
+// SetBlockGuard 设置投递群组消息前的屏蔽检查。
+func (h *Hub) SetBlockGuard(guard func(userID, targetID string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.blockGuard = guard
+}
+
+// conversationClientsNoLock 返回会话所有参与者的连接，调用者需持有 hub 锁。
+func (h *Hub) conversationClientsNoLock(conversationID string) []*Client {
+	var targetClients []*Client
+	if roomID, ok := ConversationRoomID(conversationID); ok {
+		if room, ok := h.Rooms[roomID]; ok {
+			for client := range room.Clients {
+				targetClients = append(targetClients, client)
+			}
+		}
+	} else if groupID, ok := ConversationGroupID(conversationID); ok {
+		if group, ok := h.Groups[groupID]; ok {
+			for member := range group.Members {
+				for client := range h.Users[member] {
+					targetClients = append(targetClients, client)
+				}
+			}
+		}
+	} else if a, b, ok := ConversationPeers(conversationID); ok {
+		for client := range h.Users[a] {
+			targetClients = append(targetClients, client)
+		}
+		if b != a {
+			for client := range h.Users[b] {
+				targetClients = append(targetClients, client)
+			}
+		}
+	}
+	return targetClients
 }
 
 // findClient 安全地从 hub 的 Users map 中检索客户端。
@@ -351,3 +477,112 @@ func (h *Hub) findClientNoLock(userId, deviceId string) (*Client, error) {
 	}
 	return nil, ClientNotFindError
 }
+
+// InRoom 判断用户是否有任一设备在房间中。
+func (h *Hub) InRoom(roomID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return false
+	}
+	for client := range room.Clients {
+		if client.UserId == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SharesConversation 判断两个用户是否在同一个房间或群组中。
+func (h *Hub) SharesConversation(userID, otherID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for roomID := range h.UserRooms[userID] {
+		if h.UserRooms[otherID][roomID] {
+			return true
+		}
+	}
+	for _, group := range h.Groups {
+		if group.Members[userID] && group.Members[otherID] {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRoomIDs 返回用户当前有设备在其中的房间ID。
+func (h *Hub) UserRoomIDs(userID string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var roomIDs []string
+	for roomID, room := range h.Rooms {
+		for client := range room.Clients {
+			if client.UserId == userID {
+				roomIDs = append(roomIDs, roomID)
+				break
+			}
+		}
+	}
+	return roomIDs
+}
+
+// RoomName 返回房间名称。
+func (h *Hub) RoomName(roomID string) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return "", false
+	}
+	return room.Name, true
+}
+
+// UserName 返回在线用户的名称。
+func (h *Hub) UserName(userID string) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.Users[userID] {
+		return client.UserName, true
+	}
+	return "", false
+}
+
+// RoomUserIDs 返回在给定房间中有设备的用户ID，已去重。
+func (h *Hub) RoomUserIDs(roomIDs []string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, roomID := range roomIDs {
+		room, ok := h.Rooms[roomID]
+		if !ok {
+			continue
+		}
+		for client := range room.Clients {
+			if !seen[client.UserId] {
+				seen[client.UserId] = true
+				userIDs = append(userIDs, client.UserId)
+			}
+		}
+	}
+	return userIDs
+}
+
+// OnlineUserIDs 返回当前至少有一个设备在线的用户ID。
+func (h *Hub) OnlineUserIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	userIDs := make([]string, 0, len(h.Users))
+	for userID := range h.Users {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
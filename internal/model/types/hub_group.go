@@ -0,0 +1,117 @@
+package types
+
+import (
+	"errors"
+)
+
+var (
+	GroupNotFindError   = errors.New("group not find")
+	NotGroupMemberError = errors.New("not a member of the group")
+	NotGroupOwnerError  = errors.New("not the owner of the group")
+	GroupTooLargeError  = errors.New("too many group members")
+)
+
+// 群组操作需要把校验结果返回给调用者，因此直接加锁修改，而不是像房间一样通过 channel 异步处理。
+
+// CreateGroup 保存新建的群组，返回其副本。
+func (h *Hub) CreateGroup(group *Group) *Group {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.Groups[group.ID] = group
+	return group.snapshot()
+}
+
+// Group 返回群组的副本。
+func (h *Hub) Group(groupID string) (*Group, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	group, ok := h.Groups[groupID]
+	if !ok {
+		return nil, false
+	}
+	return group.snapshot(), true
+}
+
+// InGroup 判断用户是否是群组成员。
+func (h *Hub) InGroup(groupID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	group, ok := h.Groups[groupID]
+	return ok && group.Members[userID]
+}
+
+// UserGroups 返回用户所在的所有群组的副本。
+func (h *Hub) UserGroups(userID string) []*Group {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var groups []*Group
+	for _, group := range h.Groups {
+		if group.Members[userID] {
+			groups = append(groups, group.snapshot())
+		}
+	}
+	return groups
+}
+
+// AddGroupMembers 由群组成员 actorID 添加新成员，添加后成员数不能超过 maxMembers。
+// 已经是成员的用户不计入新增人数。
+func (h *Hub) AddGroupMembers(groupID, actorID string, members []string, maxMembers int) (*Group, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	group, ok := h.Groups[groupID]
+	if !ok {
+		return nil, GroupNotFindError
+	}
+	if !group.Members[actorID] {
+		return nil, NotGroupMemberError
+	}
+	added := 0
+	for _, member := range members {
+		if !group.Members[member] {
+			added++
+		}
+	}
+	if len(group.Members)+added > maxMembers {
+		return nil, GroupTooLargeError
+	}
+	for _, member := range members {
+		group.Members[member] = true
+	}
+	return group.snapshot(), nil
+}
+
+// RemoveGroupMember 移除成员。群主可以移除任何人，其他成员只能移除自己（退出群组）。
+// 群主退出时由剩余成员之一接任，最后一个成员退出后群组被删除。
+func (h *Hub) RemoveGroupMember(groupID, actorID, memberID string) (*Group, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	group, ok := h.Groups[groupID]
+	if !ok {
+		return nil, GroupNotFindError
+	}
+	if !group.Members[actorID] || !group.Members[memberID] {
+		return nil, NotGroupMemberError
+	}
+	if actorID != memberID && actorID != group.OwnerID {
+		return nil, NotGroupOwnerError
+	}
+
+	delete(group.Members, memberID)
+	if len(group.Members) == 0 {
+		delete(h.Groups, groupID)
+		return group.snapshot(), nil
+	}
+	if memberID == group.OwnerID {
+		for member := range group.Members {
+			group.OwnerID = member
+			break
+		}
+	}
+	return group.snapshot(), nil
+}
@@ -0,0 +1,211 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/l-jessie/test-im/internal/model/entity"
+)
+
+var (
+	RoomNotFindError   = errors.New("room not find")
+	RoomPasswordError  = errors.New("wrong room password")
+	NotRoomMemberError = errors.New("not a member of the room")
+)
+
+// RoomSummary 是房间列表中的一项，与 GET /rooms 返回的字段一致。
+type RoomSummary struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	HasPassword bool               `json:"hasPassword"`
+	UserID      string             `json:"userId"`
+	UserName    string             `json:"userName"`
+	Count       int                `json:"count"`
+	CreateTime  entity.BizTimeFull `json:"createTime"`
+}
+
+// RoomMember 是房间中的一个设备。
+type RoomMember struct {
+	UserID   string `json:"userId"`
+	UserName string `json:"userName"`
+	DeviceID string `json:"deviceId"`
+}
+
+// RoomDelta 是房间增量事件的数据。
+// 列表事件（RoomCreated/RoomUpdated/RoomDeleted）的 Version 是房间列表的版本，
+// 成员事件（RoomMemberJoined/RoomMemberLeft）的 Version 是该房间成员的版本。
+// 客户端发现版本不连续时应重新请求 GET /rooms 或 GET /rooms/:roomId。
+type RoomDelta struct {
+	Version int64        `json:"version"`
+	RoomID  string       `json:"roomId"`
+	Room    *RoomSummary `json:"room,omitempty"`
+	Member  *RoomMember  `json:"member,omitempty"`
+}
+
+func (r *Room) summary() *RoomSummary {
+	return &RoomSummary{
+		ID:          r.ID,
+		Name:        r.Name,
+		HasPassword: r.Password != "",
+		UserID:      r.UserID,
+		UserName:    r.UserName,
+		Count:       len(r.Clients),
+		CreateTime:  entity.BizTimeFull(r.CreateTime),
+	}
+}
+
+func roomMember(client *Client) *RoomMember {
+	return &RoomMember{
+		UserID:   client.UserId,
+		UserName: client.UserName,
+		DeviceID: client.DeviceId,
+	}
+}
+
+// RoomList 返回所有房间和房间列表的版本。
+func (h *Hub) RoomList() ([]*RoomSummary, int64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make([]*RoomSummary, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room.summary())
+	}
+	return rooms, h.roomListVersion
+}
+
+// RoomDetail 返回房间、房间中的设备和房间成员的版本。
+func (h *Hub) RoomDetail(roomID string) (*RoomSummary, []*RoomMember, int64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return nil, nil, 0, false
+	}
+	members := make([]*RoomMember, 0, len(room.Clients))
+	for client := range room.Clients {
+		members = append(members, roomMember(client))
+	}
+	return room.summary(), members, room.Version, true
+}
+
+// AddRoom 创建房间，与通过 CreateRoom channel 发送事件相同，但同步完成。
+func (h *Hub) AddRoom(room *Room) *RoomSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.createRoomNoLock(room)
+	return room.summary()
+}
+
+// JoinClientRoom 把指定的连接加入房间并返回加入后的房间。
+// 与通过 JoinRoom channel 发送事件不同，它校验密码并同步返回结果，连接必须已经注册。
+func (h *Hub) JoinClientRoom(client *Client, roomID, password string) (*RoomSummary, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.Clients[client] {
+		return nil, ClientNotFindError
+	}
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return nil, RoomNotFindError
+	}
+	if room.Password != "" && room.Password != password {
+		return nil, RoomPasswordError
+	}
+
+	h.joinRoomNoLock(room, client)
+	return room.summary(), nil
+}
+
+// LeaveClientRoom 把指定的连接移出房间并返回离开后的房间，房间被删除时 Count 为 0。
+// 连接不在房间中时返回 NotRoomMemberError，不能借此删除别人的空房间。
+func (h *Hub) LeaveClientRoom(client *Client, roomID string) (*RoomSummary, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.Rooms[roomID]
+	if !ok {
+		return nil, RoomNotFindError
+	}
+
+	if err := h.leaveRoomNoLock(room, client); err != nil {
+		return nil, err
+	}
+	return room.summary(), nil
+}
+
+// roomListChangedNoLock 增加房间列表版本，并把列表事件发给接收房间增量事件的连接。
+// 房间被删除时 room 为空。调用者需持有 hub 写锁。
+func (h *Hub) roomListChangedNoLock(t MessageEventType, roomID string, room *Room) {
+	h.roomListVersion++
+	delta := &RoomDelta{Version: h.roomListVersion, RoomID: roomID}
+	if room != nil {
+		delta.Room = room.summary()
+	}
+
+	var targetClients []*Client
+	for client := range h.Clients {
+		if client.RoomDeltas() {
+			targetClients = append(targetClients, client)
+		}
+	}
+	sendRoomDelta(targetClients, NewMessageEvent(MessageTypeGlobal, newRoomDeltaEvent(t, delta)))
+}
+
+// roomMembersChangedNoLock 增加房间成员版本，并把成员事件发给房间中接收增量事件的连接。
+// 调用者需持有 hub 写锁。
+func (h *Hub) roomMembersChangedNoLock(t MessageEventType, room *Room, member *Client) {
+	room.Version++
+	delta := &RoomDelta{
+		Version: room.Version,
+		RoomID:  room.ID,
+		Room:    room.summary(),
+		Member:  roomMember(member),
+	}
+
+	var targetClients []*Client
+	for client := range room.Clients {
+		if client.RoomDeltas() {
+			targetClients = append(targetClients, client)
+		}
+	}
+	sendRoomDelta(targetClients, NewUpdateMessage(RoomConversationID(room.ID), newRoomDeltaEvent(t, delta)))
+}
+
+// sendLegacyRoomEventNoLock 把 ReloadRooms/ReloadRoomsDetail 发给没有改用增量事件的旧客户端。
+func (h *Hub) sendLegacyRoomEventNoLock(event *MessageEvent) {
+	var targetClients []*Client
+	for client := range h.Clients {
+		if !client.RoomDeltas() {
+			targetClients = append(targetClients, client)
+		}
+	}
+	sendRoomDelta(targetClients, NewMessageEvent(MessageTypeGlobal, event))
+}
+
+func newRoomDeltaEvent(t MessageEventType, delta *RoomDelta) *MessageEvent {
+	marshal, err := json.Marshal(delta)
+	if err != nil {
+		log.Printf("room delta json marshal error: %v", err)
+	}
+	return NewMessageEventPayload(t, marshal)
+}
+
+func sendRoomDelta(targetClients []*Client, msg *Message) {
+	if len(targetClients) == 0 {
+		return
+	}
+	marshal, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("room event json marshal error: %v", err)
+		return
+	}
+	f := newFrame(marshal, msg)
+	for _, client := range targetClients {
+		_ = client.sendFrame(f)
+	}
+}
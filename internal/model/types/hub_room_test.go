@@ -0,0 +1,204 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// roomEvent 是连接收到的一个房间事件，旧客户端的 ReloadRooms/ReloadRoomsDetail 没有版本。
+type roomEvent struct {
+	Type    MessageEventType
+	Version int64
+}
+
+func newTestClient(h *Hub, userID, deviceID string, roomDeltas bool) *Client {
+	client := NewClient(h, nil, userID, userID, deviceID)
+	client.SetRoomDeltas(roomDeltas)
+	registerClient(h, &RegisterEvent{UserId: userID, Client: client})
+	return client
+}
+
+// drainRoomEvents 取出连接发送队列中的所有房间事件。
+func drainRoomEvents(t *testing.T, client *Client) []roomEvent {
+	t.Helper()
+
+	var events []roomEvent
+	for {
+		select {
+		case out := <-client.send:
+			var message Message
+			if err := json.Unmarshal(out.frame.data, &message); err != nil {
+				t.Fatalf("unmarshal frame: %v", err)
+			}
+			if message.MessageEvent == nil {
+				continue
+			}
+			event := roomEvent{Type: message.MessageEvent.Type}
+			if event.Type >= RoomCreated && event.Type <= RoomMemberLeft {
+				var delta struct {
+					Version int64 `json:"version"`
+				}
+				if err := json.Unmarshal(message.MessageEvent.Data, &delta); err != nil {
+					t.Fatalf("unmarshal room delta: %v", err)
+				}
+				event.Version = delta.Version
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRoomDeltas(t *testing.T) {
+	cases := []struct {
+		name string
+		// run 在 watcher 和 legacy 之外的连接上操作房间，alice 有两个设备，bob 有一个
+		run        func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client)
+		wantDeltas []roomEvent // watcher 收到的增量事件
+		wantLegacy []roomEvent // legacy 收到的旧事件
+	}{
+		{
+			name: "create and join",
+			run: func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client) {
+				h.AddRoom(NewRoom("r1", "lobby", "", "alice", "alice"))
+				mustJoin(t, h, alice1, "r1")
+			},
+			wantDeltas: []roomEvent{{RoomCreated, 1}, {RoomUpdated, 2}},
+			wantLegacy: []roomEvent{{ReloadRooms, 0}, {ReloadRoomsDetail, 0}, {ReloadRooms, 0}},
+		},
+		{
+			name: "last leave deletes room",
+			run: func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client) {
+				h.AddRoom(NewRoom("r1", "lobby", "", "alice", "alice"))
+				mustJoin(t, h, alice1, "r1")
+				mustLeave(t, h, alice1, "r1")
+			},
+			wantDeltas: []roomEvent{{RoomCreated, 1}, {RoomUpdated, 2}, {RoomDeleted, 3}},
+			wantLegacy: []roomEvent{{ReloadRooms, 0}, {ReloadRoomsDetail, 0}, {ReloadRooms, 0}, {ReloadRoomsDetail, 0}},
+		},
+		{
+			name: "member versions inside room",
+			run: func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client) {
+				h.AddRoom(NewRoom("r1", "lobby", "", "alice", "alice"))
+				mustJoin(t, h, watcher, "r1")
+				mustJoin(t, h, alice1, "r1")
+				mustJoin(t, h, alice2, "r1")
+				mustLeave(t, h, alice1, "r1")
+			},
+			wantDeltas: []roomEvent{
+				{RoomCreated, 1},
+				{RoomMemberJoined, 1}, {RoomUpdated, 2},
+				{RoomMemberJoined, 2}, {RoomUpdated, 3},
+				{RoomMemberJoined, 3}, {RoomUpdated, 4},
+				{RoomMemberLeft, 4}, {RoomUpdated, 5},
+			},
+			wantLegacy: []roomEvent{
+				{ReloadRooms, 0},
+				{ReloadRoomsDetail, 0}, {ReloadRooms, 0},
+				{ReloadRoomsDetail, 0}, {ReloadRooms, 0},
+				{ReloadRoomsDetail, 0}, {ReloadRooms, 0},
+				{ReloadRoomsDetail, 0},
+			},
+		},
+		{
+			name: "joining twice does not bump versions",
+			run: func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client) {
+				h.AddRoom(NewRoom("r1", "lobby", "", "alice", "alice"))
+				mustJoin(t, h, alice1, "r1")
+				mustJoin(t, h, alice1, "r1")
+			},
+			wantDeltas: []roomEvent{{RoomCreated, 1}, {RoomUpdated, 2}},
+			wantLegacy: []roomEvent{{ReloadRooms, 0}, {ReloadRoomsDetail, 0}, {ReloadRooms, 0}, {ReloadRoomsDetail, 0}, {ReloadRooms, 0}},
+		},
+		{
+			name: "leaving a room the connection is not in",
+			run: func(t *testing.T, h *Hub, watcher, alice1, alice2, bob *Client) {
+				h.AddRoom(NewRoom("r1", "lobby", "", "alice", "alice"))
+				mustJoin(t, h, alice1, "r1")
+				if _, err := h.LeaveClientRoom(bob, "r1"); !errors.Is(err, NotRoomMemberError) {
+					t.Fatalf("leave: err = %v, want %v", err, NotRoomMemberError)
+				}
+				if _, _, _, ok := h.RoomDetail("r1"); !ok {
+					t.Fatal("room was deleted by a non-member")
+				}
+			},
+			wantDeltas: []roomEvent{{RoomCreated, 1}, {RoomUpdated, 2}},
+			wantLegacy: []roomEvent{{ReloadRooms, 0}, {ReloadRoomsDetail, 0}, {ReloadRooms, 0}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHub()
+			watcher := newTestClient(h, "watcher", "w1", true)
+			legacy := newTestClient(h, "legacy", "l1", false)
+			alice1 := newTestClient(h, "alice", "a1", true)
+			alice2 := newTestClient(h, "alice", "a2", true)
+			bob := newTestClient(h, "bob", "b1", true)
+
+			tc.run(t, h, watcher, alice1, alice2, bob)
+
+			if got := drainRoomEvents(t, watcher); !reflect.DeepEqual(got, tc.wantDeltas) {
+				t.Errorf("delta events = %v, want %v", got, tc.wantDeltas)
+			}
+			if got := drainRoomEvents(t, legacy); !reflect.DeepEqual(got, tc.wantLegacy) {
+				t.Errorf("legacy events = %v, want %v", got, tc.wantLegacy)
+			}
+
+			// 版本与 GET /rooms 返回的版本一致，客户端可以据此对齐
+			_, listVersion := h.RoomList()
+			if len(tc.wantDeltas) > 0 {
+				if last := lastListVersion(tc.wantDeltas); listVersion != last {
+					t.Errorf("room list version = %d, want %d", listVersion, last)
+				}
+			}
+		})
+	}
+}
+
+func TestLeaveKeepsRoomOfOtherDevices(t *testing.T) {
+	h := NewHub()
+	alice1 := newTestClient(h, "alice", "a1", true)
+	alice2 := newTestClient(h, "alice", "a2", true)
+
+	h.AddRoom(NewRoom("r1", "lobby", "", "bob", "bob"))
+	mustJoin(t, h, alice1, "r1")
+	mustJoin(t, h, alice2, "r1")
+
+	mustLeave(t, h, alice1, "r1")
+	if !h.UserRooms["alice"]["r1"] {
+		t.Fatal("room removed from alice while another device is still inside")
+	}
+
+	mustLeave(t, h, alice2, "r1")
+	if h.UserRooms["alice"]["r1"] {
+		t.Fatal("room kept for alice after all devices left")
+	}
+}
+
+func mustJoin(t *testing.T, h *Hub, client *Client, roomID string) {
+	t.Helper()
+	if _, err := h.JoinClientRoom(client, roomID, ""); err != nil {
+		t.Fatalf("join %s: %v", roomID, err)
+	}
+}
+
+func mustLeave(t *testing.T, h *Hub, client *Client, roomID string) {
+	t.Helper()
+	if _, err := h.LeaveClientRoom(client, roomID); err != nil {
+		t.Fatalf("leave %s: %v", roomID, err)
+	}
+}
+
+func lastListVersion(events []roomEvent) int64 {
+	var version int64
+	for _, event := range events {
+		if event.Type == RoomCreated || event.Type == RoomUpdated || event.Type == RoomDeleted {
+			version = event.Version
+		}
+	}
+	return version
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern 匹配消息文本中的 @用户ID 或 @用户名。
+var mentionPattern = regexp.MustCompile(`@([^\s@]+)`)
+
+// Mention 是被提及的用户收到的通知内容。
+type Mention struct {
+	MessageID      string `json:"messageId"`
+	ConversationID string `json:"conversationId"`
+	From           string `json:"from"`
+	Seq            int64  `json:"seq,omitempty"`
+	Timestamp      int64  `json:"time,omitempty"`
+}
+
+func NewMention(msg *Message) *Mention {
+	return &Mention{
+		MessageID:      msg.ID,
+		ConversationID: ConversationIDOf(msg),
+		From:           msg.From,
+		Seq:            msg.Seq,
+		Timestamp:      msg.Timestamp,
+	}
+}
+
+// MentionTokens 返回文本中 @ 之后的内容，去除了末尾的标点。
+func MentionTokens(text string) []string {
+	var tokens []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		token := strings.TrimRight(match[1], ",.;:!?，。；：！？、)")
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
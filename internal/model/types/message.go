@@ -13,17 +13,50 @@ const (
 	MessageTypeUser
 	MessageTypeGlobal
 	MessageTypeSystem
-	MessageTypeJoinRoom
-	MessageTypeLeaveRoom
+	MessageTypeJoinRoom   // 当前连接加入房间，参数在 RoomCommand 中，结果在 Room 中
+	MessageTypeLeaveRoom  // 当前连接退出房间
+	MessageTypeReceipt    // 送达/已读回执
+	MessageTypeUpdate     // 会话内的事件，To 为会话ID，发给会话所有参与者
+	MessageTypeVote       // 投票操作
+	MessageTypeGroup      // 群组消息，To 为群组ID
+	MessageTypeError      // 客户端发送的消息被拒绝，只发给发送的设备
+	MessageTypeSubscribe  // 订阅在线状态和房间增量事件，只对当前连接生效
+	MessageTypeCreateRoom // 创建房间
 )
 
 type Message struct {
+	ID           string        `json:"id,omitempty"`  // 服务端生成的消息ID
+	Seq          int64         `json:"seq,omitempty"` // 会话内的顺序号
 	Type         MessageType   `json:"type"`
 	Payload      *Payload      `json:"payload"`
 	From         string        `json:"from"`
 	To           string        `json:"to"`
 	MessageEvent *MessageEvent `json:"messageEvent"`
+	Receipt      *Receipt      `json:"receipt,omitempty"`
+	Vote         *Vote         `json:"vote,omitempty"`
+	Forwarded    *ForwardInfo  `json:"forwarded,omitempty"` // 转发消息的来源
+	Error        *ErrorInfo    `json:"error,omitempty"`
+	Subscribe    *Subscription `json:"subscribe,omitempty"`
+	RoomCommand  *RoomCommand  `json:"roomCommand,omitempty"`
+	Room         *RoomSummary  `json:"room,omitempty"` // 房间命令的结果
 	Timestamp    int64         `json:"time,omitempty"`
+	TTL          int64         `json:"ttl,omitempty"`      // 客户端指定的存活秒数
+	ExpireAt     int64         `json:"expireAt,omitempty"` // 服务端计算的过期时间，到期后删除
+}
+
+// RoomCommand 是通过 websocket 创建、加入或退出房间的参数。
+type RoomCommand struct {
+	RoomID   string `json:"roomId,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ForwardInfo 记录被转发消息的原作者和来源会话。
+type ForwardInfo struct {
+	MessageID      string `json:"messageId"`
+	From           string `json:"from"` // 原消息作者
+	ConversationID string `json:"conversationId"`
+	Timestamp      int64  `json:"time"` // 原消息发送时间
 }
 
 func NewMessage(t MessageType, payload *Payload, from string, to string) *Message {
@@ -43,3 +76,51 @@ func NewMessageEvent(t MessageType, messageEvent *MessageEvent) *Message {
 		Timestamp:    time.Now().Unix(),
 	}
 }
+
+func NewReceiptMessage(from, to string, receipt *Receipt) *Message {
+	return &Message{
+		Type:      MessageTypeReceipt,
+		From:      from,
+		To:        to,
+		Receipt:   receipt,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// NewSystemMessage 构造发给某个用户所有设备的系统事件。
+func NewSystemMessage(to string, messageEvent *MessageEvent) *Message {
+	return &Message{
+		Type:         MessageTypeSystem,
+		To:           to,
+		MessageEvent: messageEvent,
+		Timestamp:    time.Now().Unix(),
+	}
+}
+
+// ErrorInfo 说明消息被拒绝的原因，Target 为被拒绝消息的 To。
+type ErrorInfo struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Type    MessageType `json:"type"`
+	Target  string      `json:"target,omitempty"`
+}
+
+// NewErrorMessage 构造发给发送者的错误帧。
+func NewErrorMessage(to string, info *ErrorInfo) *Message {
+	return &Message{
+		Type:      MessageTypeError,
+		To:        to,
+		Error:     info,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// NewUpdateMessage 构造发给会话所有参与者的事件。
+func NewUpdateMessage(conversationID string, messageEvent *MessageEvent) *Message {
+	return &Message{
+		Type:         MessageTypeUpdate,
+		To:           conversationID,
+		MessageEvent: messageEvent,
+		Timestamp:    time.Now().Unix(),
+	}
+}
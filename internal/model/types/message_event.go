@@ -10,6 +10,25 @@ const (
 	ReloadUsers MessageEventType = iota
 	ReloadRoomsDetail
 	ReloadRooms
+	ReadStateSync // 已读水位在某个设备上发生了变化
+	MessageDeleted
+	ConversationSettingsChanged
+	PollUpdated
+	MessageUpdated
+	GroupUpdated          // 群组信息或成员发生变化
+	GroupRemoved          // 当前用户被移出群组
+	AnnouncementPublished // 管理员公告
+	FriendRequestUpdated  // 收到好友请求或请求状态变化
+	ContactsChanged       // 联系人列表发生变化
+	PresenceChanged       // 订阅的用户在线状态或自定义状态发生变化，Data 为 []Presence
+	RoomCreated           // 以下为房间增量事件，Data 为 RoomDelta
+	RoomUpdated
+	RoomDeleted
+	RoomMemberJoined
+	RoomMemberLeft
+	DeviceRevoked  // 用户的某个设备被移除，Data 为设备ID
+	ProfileUpdated // 用户资料发生变化，Data 为 UserVO
+	Mentioned      // 用户在房间或群组消息中被提及，Data 为 Mention
 )
 
 type MessageEvent struct {
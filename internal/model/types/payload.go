@@ -8,12 +8,40 @@ const (
 	PayloadTypeText PayloadType = iota
 	PayloadTypeImage
 	PayloadTypeFile
+	PayloadTypePoll     // 投票，Content 为 Poll
+	PayloadTypeMarkdown // Markdown 文本，服务端渲染为 HTML
 )
 
 type Payload struct {
 	Type    PayloadType     `json:"type"`
 	Content json.RawMessage `json:"data"`
 	File    []byte          `json:"file"`
+	FileID  string          `json:"fileId,omitempty"` // 通过 HTTP 上传的文件ID
+
+	FileMeta *FileMeta `json:"fileMeta,omitempty"` // 服务端根据 FileID 填充的文件信息
+	HTML     string    `json:"html,omitempty"`     // 服务端渲染并过滤后的 HTML，仅 Markdown 消息
+
+	LinkPreviews []*LinkPreview `json:"linkPreviews,omitempty"` // 服务端抓取的链接预览
+	Mentions     []string       `json:"mentions,omitempty"`     // 服务端解析出的被提及用户ID，仅房间和群组消息
+}
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+}
+
+type FileMeta struct {
+	Name            string `json:"name"`
+	Size            int64  `json:"size"`
+	MIME            string `json:"mime"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	ThumbnailURL    string `json:"thumbnailUrl,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnailWidth,omitempty"`
+	ThumbnailHeight int    `json:"thumbnailHeight,omitempty"`
 }
 
 func NewPayload(t PayloadType, content []byte) *Payload {
@@ -22,3 +50,26 @@ func NewPayload(t PayloadType, content []byte) *Payload {
 		Content: content,
 	}
 }
+
+// Clone 深拷贝 payload，发送时服务端会修改 FileMeta、HTML 等字段，不能与保存的副本共享。
+func (p *Payload) Clone() *Payload {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	copied.Content = append(json.RawMessage(nil), p.Content...)
+	copied.File = append([]byte(nil), p.File...)
+	if p.FileMeta != nil {
+		fileMeta := *p.FileMeta
+		copied.FileMeta = &fileMeta
+	}
+	copied.Mentions = append([]string(nil), p.Mentions...)
+	if p.LinkPreviews != nil {
+		copied.LinkPreviews = make([]*LinkPreview, 0, len(p.LinkPreviews))
+		for _, preview := range p.LinkPreviews {
+			linkPreview := *preview
+			copied.LinkPreviews = append(copied.LinkPreviews, &linkPreview)
+		}
+	}
+	return &copied
+}
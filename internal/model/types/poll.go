@@ -0,0 +1,31 @@
+package types
+
+// Poll 是投票消息的内容，作为 PayloadTypePoll 消息的 Content 传输。
+// 客户端创建时只需要填写选项文本，选项ID由服务端分配。
+type Poll struct {
+	Question string        `json:"question"`
+	Options  []*PollOption `json:"options"`
+	Multiple bool          `json:"multiple"`          // 是否允许多选
+	CloseAt  int64         `json:"closeAt,omitempty"` // 截止时间，0 表示不截止
+}
+
+type PollOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Vote 是客户端的投票操作，OptionIDs 为空表示撤回投票。
+type Vote struct {
+	PollID    string   `json:"pollId"`
+	OptionIDs []string `json:"optionIds"`
+}
+
+// PollResult 是服务端统计的投票结果。
+type PollResult struct {
+	PollID         string         `json:"pollId"`
+	ConversationID string         `json:"conversationId"`
+	Counts         map[string]int `json:"counts"` // 选项ID -> 票数
+	Voters         int            `json:"voters"` // 参与投票的人数
+	Closed         bool           `json:"closed"`
+	MyVote         []string       `json:"myVote,omitempty"` // 查询者自己的选择
+}
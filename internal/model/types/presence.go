@@ -0,0 +1,24 @@
+package types
+
+type PresenceState string
+
+const (
+	PresenceOnline    PresenceState = "online"
+	PresenceAway      PresenceState = "away"
+	PresenceBusy      PresenceState = "busy" // 请勿打扰
+	PresenceInvisible PresenceState = "invisible"
+	PresenceOffline   PresenceState = "offline"
+)
+
+// Presence 是其他用户看到的在线状态。隐身的用户对其他人显示为离线。
+type Presence struct {
+	UserID         string        `json:"userId"`
+	State          PresenceState `json:"state"`
+	StatusText     string        `json:"statusText,omitempty"`     // 自定义状态
+	StatusExpireAt int64         `json:"statusExpireAt,omitempty"` // 自定义状态的过期时间，0 表示不过期
+}
+
+// Visible 判断其他用户是否能看到该用户在线。
+func (p *Presence) Visible() bool {
+	return p.State != PresenceOffline
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"time"
+)
+
+type ReceiptType int
+
+const (
+	ReceiptDelivered ReceiptType = iota // 已送达
+	ReceiptRead                         // 已读
+)
+
+type Receipt struct {
+	Type           ReceiptType `json:"type"`
+	MessageID      string      `json:"messageId"`
+	ConversationID string      `json:"conversationId,omitempty"`
+	Seq            int64       `json:"seq,omitempty"`
+	UserID         string      `json:"userId,omitempty"`   // 产生回执的用户
+	DeviceID       string      `json:"deviceId,omitempty"` // 产生回执的设备
+	Timestamp      int64       `json:"time,omitempty"`
+}
+
+func NewReceipt(t ReceiptType, msg *Message, userID, deviceID string) *Receipt {
+	return &Receipt{
+		Type:           t,
+		MessageID:      msg.ID,
+		ConversationID: ConversationIDOf(msg),
+		Seq:            msg.Seq,
+		UserID:         userID,
+		DeviceID:       deviceID,
+		Timestamp:      time.Now().Unix(),
+	}
+}
@@ -12,6 +12,7 @@ type Room struct {
 	UserName   string
 	Clients    map[*Client]bool
 	CreateTime time.Time
+	Version    int64 // 房间成员的版本，每次有设备加入或离开时增加
 }
 
 func NewRoom(id, name, password, ownerUserID, ownerUserName string) *Room {
@@ -0,0 +1,17 @@
+package types
+
+// Subscription 是客户端发送的订阅变更，只对当前连接生效。
+//
+// Add/Remove 修改订阅在线状态的用户，出现其中任一字段（可以是空数组）后，
+// 连接只收到订阅用户的状态变化，不再收到全局的 ReloadUsers。
+// Rooms 为 true 时连接改为接收房间的增量事件，不再收到 ReloadRooms/ReloadRoomsDetail。
+type Subscription struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+	Rooms  *bool    `json:"rooms,omitempty"`
+}
+
+// HasPresence 判断订阅变更是否涉及在线状态。
+func (s *Subscription) HasPresence() bool {
+	return s.Add != nil || s.Remove != nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// AnnouncementRecord 是一条公告及其投递目标。
+// All 为 true 时发给所有用户，否则只发给 Recipients 中的用户（房间目标在发布时展开为成员）。
+type AnnouncementRecord struct {
+	Announcement *types.Announcement
+	CreatorID    string
+	All          bool
+	RoomIDs      []string
+	UserIDs      []string
+	Recipients   map[string]bool
+}
+
+func (r *AnnouncementRecord) targets(userID string) bool {
+	return r.All || r.Recipients[userID]
+}
+
+type AnnouncementStore struct {
+	mu sync.RWMutex
+
+	announcements []*AnnouncementRecord      // 按发布时间排列
+	delivered     map[string]map[string]bool // 公告ID -> 已投递的用户ID
+}
+
+func NewAnnouncementStore() *AnnouncementStore {
+	return &AnnouncementStore{
+		delivered: make(map[string]map[string]bool),
+	}
+}
+
+// Save 保存公告，记录不会再被修改，可以直接共享。
+func (s *AnnouncementStore) Save(record *AnnouncementRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.announcements = append(s.announcements, record)
+	s.delivered[record.Announcement.ID] = make(map[string]bool)
+}
+
+// MarkDelivered 记录公告已经投递给这些用户。
+func (s *AnnouncementStore) MarkDelivered(announcementID string, userIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivered, ok := s.delivered[announcementID]
+	if !ok {
+		return
+	}
+	for _, userID := range userIDs {
+		delivered[userID] = true
+	}
+}
+
+// TakePending 返回发给用户但还没有投递的公告（最多 limit 条，取最新的），并标记为已投递。
+func (s *AnnouncementStore) TakePending(userID string, limit int) []*types.Announcement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []*types.Announcement
+	for i := len(s.announcements) - 1; i >= 0 && len(pending) < limit; i-- {
+		record := s.announcements[i]
+		delivered := s.delivered[record.Announcement.ID]
+		if !record.targets(userID) || delivered[userID] {
+			continue
+		}
+		delivered[userID] = true
+		pending = append(pending, record.Announcement)
+	}
+
+	// 按发布时间先后补发
+	for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+		pending[i], pending[j] = pending[j], pending[i]
+	}
+	return pending
+}
+
+// ListByUser 返回发给用户的所有公告，最新的在前。
+func (s *AnnouncementStore) ListByUser(userID string) []*types.Announcement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*types.Announcement
+	for i := len(s.announcements) - 1; i >= 0; i-- {
+		if s.announcements[i].targets(userID) {
+			result = append(result, s.announcements[i].Announcement)
+		}
+	}
+	return result
+}
+
+// List 返回所有公告记录，最新的在前。
+func (s *AnnouncementStore) List() []*AnnouncementRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*AnnouncementRecord, 0, len(s.announcements))
+	for i := len(s.announcements) - 1; i >= 0; i-- {
+		result = append(result, s.announcements[i])
+	}
+	return result
+}
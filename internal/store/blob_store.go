@@ -0,0 +1,129 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	BlobNotFindError = errors.New("blob not find")
+)
+
+// BlobStore 按内容寻址保存文件数据，key 为内容的 sha256 十六进制值。
+// 相同内容只保存一份，由引用计数决定何时删除：每次 Put 和 Retain 增加一个引用，
+// Delete 释放一个引用，没有引用时才删除数据。
+// 本地磁盘之外的实现（例如兼容 S3 的对象存储）只需实现该接口。
+type BlobStore interface {
+	// Put 保存数据并返回其 key 和字节数，相同内容只会保存一份。
+	Put(r io.Reader) (key string, size int64, err error)
+	// Open 打开 key 对应的数据。
+	Open(key string) (io.ReadCloser, error)
+	// Retain 为已保存的数据增加一个引用，用于多个记录共享同一份数据。
+	Retain(key string)
+	// Delete 释放一个引用，最后一个引用释放后删除数据。
+	Delete(key string) error
+}
+
+// LocalBlobStore 把数据保存在本地目录中，按 key 的前两位分目录。
+type LocalBlobStore struct {
+	dir string
+
+	mu   sync.Mutex     // 保证移动、删除文件与引用计数一致
+	refs map[string]int // key -> 引用数
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{dir: dir, refs: make(map[string]int)}, nil
+}
+
+func (s *LocalBlobStore) Put(r io.Reader) (string, int64, error) {
+	// 先写入临时文件，计算出哈希后再移动到最终位置
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(path); err != nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", 0, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return "", 0, err
+		}
+	}
+	s.refs[key]++
+	return key, size, nil
+}
+
+func (s *LocalBlobStore) Retain(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs[key]++
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	if !validBlobKey(key) {
+		return BlobNotFindError
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refs[key]--; s.refs[key] > 0 {
+		return nil
+	}
+	delete(s.refs, key)
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) Open(key string) (io.ReadCloser, error) {
+	if !validBlobKey(key) {
+		return nil, BlobNotFindError
+	}
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, BlobNotFindError
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+// validBlobKey 防止构造出的 key 逃出存储目录。
+func validBlobKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+type FriendRequestStatus string
+
+const (
+	FriendRequestPending   FriendRequestStatus = "pending"
+	FriendRequestAccepted  FriendRequestStatus = "accepted"
+	FriendRequestDeclined  FriendRequestStatus = "declined"
+	FriendRequestCancelled FriendRequestStatus = "cancelled"
+)
+
+var (
+	FriendRequestNotFindError = errors.New("friend request not find")
+	FriendRequestHandledError = errors.New("friend request already handled")
+)
+
+type FriendRequest struct {
+	ID         string              `json:"id"`
+	From       string              `json:"from"`
+	To         string              `json:"to"`
+	Message    string              `json:"message,omitempty"` // 验证消息
+	Status     FriendRequestStatus `json:"status"`
+	CreateTime int64               `json:"createTime"`
+	UpdateTime int64               `json:"updateTime"`
+}
+
+type ContactStore struct {
+	mu sync.RWMutex
+
+	requests map[string]*FriendRequest   // 请求ID -> 好友请求
+	contacts map[string]map[string]int64 // 用户ID -> 联系人用户ID -> 成为联系人的时间
+}
+
+func NewContactStore() *ContactStore {
+	return &ContactStore{
+		requests: make(map[string]*FriendRequest),
+		contacts: make(map[string]map[string]int64),
+	}
+}
+
+// IsContact 判断两个用户是否互为联系人。
+func (s *ContactStore) IsContact(userID, otherID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.contacts[userID][otherID]
+	return ok
+}
+
+// Contacts 返回用户的联系人及成为联系人的时间。
+func (s *ContactStore) Contacts(userID string) map[string]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]int64, len(s.contacts[userID]))
+	for contactID, since := range s.contacts[userID] {
+		result[contactID] = since
+	}
+	return result
+}
+
+// RemoveContact 双向解除联系人关系，返回之前是否是联系人。
+func (s *ContactStore) RemoveContact(userID, otherID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contacts[userID][otherID]; !ok {
+		return false
+	}
+	delete(s.contacts[userID], otherID)
+	delete(s.contacts[otherID], userID)
+	return true
+}
+
+// PendingBetween 返回 from 发给 to 的待处理请求。
+func (s *ContactStore) PendingBetween(from, to string) (*FriendRequest, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, request := range s.requests {
+		if request.From == from && request.To == to && request.Status == FriendRequestPending {
+			copied := *request
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+func (s *ContactStore) SaveRequest(request *FriendRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *request
+	s.requests[request.ID] = &copied
+}
+
+func (s *ContactStore) GetRequest(requestID string) (*FriendRequest, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	request, ok := s.requests[requestID]
+	if !ok {
+		return nil, false
+	}
+	copied := *request
+	return &copied, true
+}
+
+// Resolve 把待处理的请求改为 status，接受时双方成为联系人。
+func (s *ContactStore) Resolve(requestID string, status FriendRequestStatus) (*FriendRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.requests[requestID]
+	if !ok {
+		return nil, FriendRequestNotFindError
+	}
+	if request.Status != FriendRequestPending {
+		return nil, FriendRequestHandledError
+	}
+
+	now := time.Now().Unix()
+	request.Status = status
+	request.UpdateTime = now
+	if status == FriendRequestAccepted {
+		s.addContactNoLock(request.From, request.To, now)
+		s.addContactNoLock(request.To, request.From, now)
+	}
+
+	copied := *request
+	return &copied, nil
+}
+
+func (s *ContactStore) addContactNoLock(userID, otherID string, since int64) {
+	if _, ok := s.contacts[userID]; !ok {
+		s.contacts[userID] = make(map[string]int64)
+	}
+	s.contacts[userID][otherID] = since
+}
+
+// PendingRequests 返回用户收到和发出的待处理请求，最新的在前。
+func (s *ContactStore) PendingRequests(userID string) []*FriendRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*FriendRequest
+	for _, request := range s.requests {
+		if request.Status == FriendRequestPending && (request.From == userID || request.To == userID) {
+			copied := *request
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreateTime > result[j].CreateTime
+	})
+	return result
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// ConversationSettings 是会话级别的设置，对会话所有参与者生效。
+type ConversationSettings struct {
+	ConversationID      string `json:"conversationId"`
+	DisappearingSeconds int64  `json:"disappearingSeconds"` // 新消息默认的存活秒数，0 表示不自动删除
+	UpdateUserID        string `json:"updateUserId,omitempty"`
+	UpdateTime          int64  `json:"updateTime,omitempty"`
+}
+
+type ConversationStore struct {
+	mu sync.RWMutex
+
+	settings map[string]*ConversationSettings // 会话ID -> 设置
+}
+
+func NewConversationStore() *ConversationStore {
+	return &ConversationStore{
+		settings: make(map[string]*ConversationSettings),
+	}
+}
+
+// Settings 返回会话设置，未设置过时返回默认值。
+func (s *ConversationStore) Settings(conversationID string) *ConversationSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if settings, ok := s.settings[conversationID]; ok {
+		copied := *settings
+		return &copied
+	}
+	return &ConversationSettings{ConversationID: conversationID}
+}
+
+// SetDisappearing 修改会话新消息默认的存活秒数。
+func (s *ConversationStore) SetDisappearing(conversationID, userID string, seconds int64) *ConversationSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, ok := s.settings[conversationID]
+	if !ok {
+		settings = &ConversationSettings{ConversationID: conversationID}
+		s.settings[conversationID] = settings
+	}
+	settings.DisappearingSeconds = seconds
+	settings.UpdateUserID = userID
+	settings.UpdateTime = time.Now().Unix()
+
+	copied := *settings
+	return &copied
+}
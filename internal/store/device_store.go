@@ -0,0 +1,105 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// DeviceSession 是登录时为 (用户ID, 设备ID) 签发的会话凭证，建立连接时必须携带。
+type DeviceSession struct {
+	DeviceID   string
+	Token      string
+	CreateTime int64
+	RevokeTime int64 // 被移除的时间，0 表示未被移除
+}
+
+// DeviceStore 记录每个设备的会话，被移除的会话不能再建立连接，恢复后可以继续使用。
+type DeviceStore struct {
+	mu sync.RWMutex
+
+	sessions map[string]map[string]*DeviceSession // 用户ID -> 设备ID -> 会话
+	tokens   map[string]deviceKey                 // 凭证 -> 设备
+}
+
+type deviceKey struct {
+	userID   string
+	deviceID string
+}
+
+func NewDeviceStore() *DeviceStore {
+	return &DeviceStore{
+		sessions: make(map[string]map[string]*DeviceSession),
+		tokens:   make(map[string]deviceKey),
+	}
+}
+
+// Issue 为设备签发新的会话，替换设备之前的会话。
+func (s *DeviceStore) Issue(userID, deviceID, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[userID]; !ok {
+		s.sessions[userID] = make(map[string]*DeviceSession)
+	}
+	if previous, ok := s.sessions[userID][deviceID]; ok {
+		delete(s.tokens, previous.Token)
+	}
+	s.tokens[token] = deviceKey{userID: userID, deviceID: deviceID}
+	s.sessions[userID][deviceID] = &DeviceSession{
+		DeviceID:   deviceID,
+		Token:      token,
+		CreateTime: time.Now().Unix(),
+	}
+}
+
+// Get 返回设备会话的副本。
+func (s *DeviceStore) Get(userID, deviceID string) (DeviceSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[userID][deviceID]
+	if !ok {
+		return DeviceSession{}, false
+	}
+	return *session, true
+}
+
+// Lookup 返回持有该凭证的用户ID和设备会话副本。
+func (s *DeviceStore) Lookup(token string) (string, DeviceSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.tokens[token]
+	if !ok {
+		return "", DeviceSession{}, false
+	}
+	return key.userID, *s.sessions[key.userID][key.deviceID], true
+}
+
+// Revoke 移除设备会话，设备没有会话时返回 false。
+func (s *DeviceStore) Revoke(userID, deviceID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[userID][deviceID]
+	if !ok {
+		return false
+	}
+	if session.RevokeTime == 0 {
+		session.RevokeTime = time.Now().Unix()
+	}
+	return true
+}
+
+// Restore 恢复被移除的设备会话，设备没有会话时返回 false。
+func (s *DeviceStore) Restore(userID, deviceID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[userID][deviceID]
+	if !ok {
+		return false
+	}
+	session.RevokeTime = 0
+	return true
+}
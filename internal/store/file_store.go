@@ -0,0 +1,65 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// FileRecord 是一次上传的记录，多个记录可以引用同一个 blob。
+type FileRecord struct {
+	ID             string
+	BlobKey        string
+	Name           string
+	Size           int64
+	MIME           string
+	UploaderID     string
+	ConversationID string // 文件所属的会话，只有会话成员可以下载
+	CreateTime     time.Time
+
+	// 图片的尺寸和缩略图，非图片文件为空
+	Width     int
+	Height    int
+	Thumbnail *Thumbnail
+}
+
+type Thumbnail struct {
+	BlobKey string
+	MIME    string
+	Size    int64
+	Width   int
+	Height  int
+}
+
+type FileStore struct {
+	mu sync.RWMutex
+
+	files map[string]*FileRecord // 文件ID -> 记录
+}
+
+func NewFileStore() *FileStore {
+	return &FileStore{
+		files: make(map[string]*FileRecord),
+	}
+}
+
+func (s *FileStore) Save(record *FileRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[record.ID] = record
+}
+
+func (s *FileStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.files, id)
+}
+
+func (s *FileStore) Get(id string) (*FileRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.files[id]
+	return record, ok
+}
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// MessageStore 在内存中保存会话消息，按会话维护递增的顺序号。
+type MessageStore struct {
+	mu sync.RWMutex
+
+	messages      map[string]*types.Message   // 消息ID -> 消息
+	conversations map[string][]*types.Message // 会话ID -> 按顺序号排列的消息
+	seqs          map[string]int64            // 会话ID -> 最后分配的顺序号
+	participants  map[string]map[string]bool  // 用户ID -> 参与过的会话ID
+	expiring      map[string]*types.Message   // 设置了过期时间的消息
+	index         *searchIndex                // 全文索引
+}
+
+// SearchQuery 是全文搜索的条件，除 Text 外都是可选的过滤条件。
+type SearchQuery struct {
+	Text           string
+	From           string // 发送者
+	ConversationID string
+	Start          int64 // 起始时间（含），unix 秒
+	End            int64 // 结束时间（含），unix 秒
+	Limit          int
+}
+
+func NewMessageStore() *MessageStore {
+	return &MessageStore{
+		messages:      make(map[string]*types.Message),
+		conversations: make(map[string][]*types.Message),
+		seqs:          make(map[string]int64),
+		participants:  make(map[string]map[string]bool),
+		expiring:      make(map[string]*types.Message),
+		index:         newSearchIndex(),
+	}
+}
+
+// Save 保存消息并为其分配会话内的顺序号。
+func (s *MessageStore) Save(conversationID string, msg *types.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seqs[conversationID]++
+	msg.Seq = s.seqs[conversationID]
+	s.conversations[conversationID] = append(s.conversations[conversationID], msg)
+	s.messages[msg.ID] = msg
+	if msg.ExpireAt > 0 {
+		s.expiring[msg.ID] = msg
+	}
+	s.index.add(msg)
+
+	s.addParticipantNoLock(msg.From, conversationID)
+	if msg.Type == types.MessageTypeUser {
+		s.addParticipantNoLock(msg.To, conversationID)
+	}
+}
+
+func (s *MessageStore) addParticipantNoLock(userID, conversationID string) {
+	if _, ok := s.participants[userID]; !ok {
+		s.participants[userID] = make(map[string]bool)
+	}
+	s.participants[userID][conversationID] = true
+}
+
+// Conversations 返回用户发送或接收过消息的所有会话ID。
+func (s *MessageStore) Conversations(userID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]string, 0, len(s.participants[userID]))
+	for conversationID := range s.participants[userID] {
+		result = append(result, conversationID)
+	}
+	return result
+}
+
+// Last 返回会话中的最后一条消息。
+func (s *MessageStore) Last(conversationID string) (*types.Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := s.conversations[conversationID]
+	if len(list) == 0 {
+		return nil, false
+	}
+	return list[len(list)-1], true
+}
+
+// CountUnread 统计会话中顺序号大于 afterSeq 且不是 userID 自己发送的消息数量。
+func (s *MessageStore) CountUnread(conversationID, userID string, afterSeq int64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	list := s.conversations[conversationID]
+	for i := len(list) - 1; i >= 0 && list[i].Seq > afterSeq; i-- {
+		if list[i].From != userID {
+			count++
+		}
+	}
+	return count
+}
+
+// Get 按消息ID查找消息。
+func (s *MessageStore) Get(id string) (*types.Message, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	msg, ok := s.messages[id]
+	return msg, ok
+}
+
+// Range 返回会话中顺序号在 (afterSeq, toSeq] 区间内的消息。
+func (s *MessageStore) Range(conversationID string, afterSeq, toSeq int64) []*types.Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*types.Message
+	for _, msg := range s.conversations[conversationID] {
+		if msg.Seq > afterSeq && msg.Seq <= toSeq {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// DeleteExpired 删除过期时间不晚于 now 的消息并返回它们。
+func (s *MessageStore) DeleteExpired(now int64) []*types.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted []*types.Message
+	for _, msg := range s.expiring {
+		if msg.ExpireAt <= now {
+			s.deleteNoLock(msg)
+			deleted = append(deleted, msg)
+		}
+	}
+	return deleted
+}
+
+// HasFile 判断会话中是否还有消息引用该文件。
+func (s *MessageStore) HasFile(conversationID, fileID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, msg := range s.conversations[conversationID] {
+		if msg.Payload != nil && msg.Payload.FileID == fileID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MessageStore) deleteNoLock(msg *types.Message) {
+	delete(s.messages, msg.ID)
+	delete(s.expiring, msg.ID)
+	s.index.remove(msg.ID)
+
+	conversationID := types.ConversationIDOf(msg)
+	list := s.conversations[conversationID]
+	for i, m := range list {
+		if m.ID == msg.ID {
+			s.conversations[conversationID] = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+}
+
+// Search 返回匹配查询的消息，按时间倒序排列。
+// allow 用于过滤调用者无权访问的会话，在截取 Limit 之前执行。
+func (s *MessageStore) Search(query *SearchQuery, allow func(conversationID string) bool) []*types.Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*types.Message, 0)
+	for _, msgID := range s.index.search(query.Text) {
+		msg, ok := s.messages[msgID]
+		if !ok {
+			continue
+		}
+		conversationID := types.ConversationIDOf(msg)
+		if query.From != "" && msg.From != query.From {
+			continue
+		}
+		if query.ConversationID != "" && conversationID != query.ConversationID {
+			continue
+		}
+		if (query.Start > 0 && msg.Timestamp < query.Start) || (query.End > 0 && msg.Timestamp > query.End) {
+			continue
+		}
+		if !allow(conversationID) {
+			continue
+		}
+		result = append(result, msg)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Timestamp != result[j].Timestamp {
+			return result[i].Timestamp > result[j].Timestamp
+		}
+		return result[i].Seq > result[j].Seq
+	})
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	return result
+}
+
+// SetLinkPreviews 为消息设置链接预览。
+// 已广播出去的消息对象可能仍在被编码，这里复制一份再替换，不修改原对象。
+func (s *MessageStore) SetLinkPreviews(id string, previews []*types.LinkPreview) (*types.Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok || msg.Payload == nil {
+		return nil, false
+	}
+
+	payload := *msg.Payload
+	payload.LinkPreviews = previews
+	updated := *msg
+	updated.Payload = &payload
+
+	s.messages[id] = &updated
+	if _, ok := s.expiring[id]; ok {
+		s.expiring[id] = &updated
+	}
+	list := s.conversations[types.ConversationIDOf(msg)]
+	for i, m := range list {
+		if m.ID == id {
+			list[i] = &updated
+			break
+		}
+	}
+	return &updated, true
+}
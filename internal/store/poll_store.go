@@ -0,0 +1,108 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// PollRecord 是一次投票及其所有选票，ID 与投票消息的ID相同。
+type PollRecord struct {
+	ID             string
+	ConversationID string
+	CreatorID      string
+	Poll           *types.Poll
+	Votes          map[string][]string // 用户ID -> 选择的选项ID
+}
+
+type PollStore struct {
+	mu sync.RWMutex
+
+	polls map[string]*PollRecord // 投票ID -> 投票
+}
+
+func NewPollStore() *PollStore {
+	return &PollStore{
+		polls: make(map[string]*PollRecord),
+	}
+}
+
+func (s *PollStore) Save(record *PollRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.polls[record.ID] = record
+}
+
+func (s *PollStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.polls, id)
+}
+
+// Get 返回投票，Poll 定义创建后不再修改，可以直接共享。
+func (s *PollStore) Get(id string) (*PollRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.polls[id]
+	if !ok {
+		return nil, false
+	}
+	return &PollRecord{
+		ID:             record.ID,
+		ConversationID: record.ConversationID,
+		CreatorID:      record.CreatorID,
+		Poll:           record.Poll,
+	}, true
+}
+
+// SetVote 记录用户的选择，optionIDs 为空时撤回投票，返回最新的统计结果。
+func (s *PollStore) SetVote(id, userID string, optionIDs []string) (*types.PollResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.polls[id]
+	if !ok {
+		return nil, false
+	}
+	if len(optionIDs) == 0 {
+		delete(record.Votes, userID)
+	} else {
+		record.Votes[userID] = optionIDs
+	}
+	return tallyNoLock(record), true
+}
+
+// Result 返回投票的统计结果和 userID 自己的选择。
+func (s *PollStore) Result(id, userID string) (*types.PollResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.polls[id]
+	if !ok {
+		return nil, false
+	}
+	result := tallyNoLock(record)
+	result.MyVote = append([]string(nil), record.Votes[userID]...)
+	return result, true
+}
+
+func tallyNoLock(record *PollRecord) *types.PollResult {
+	result := &types.PollResult{
+		PollID:         record.ID,
+		ConversationID: record.ConversationID,
+		Counts:         make(map[string]int, len(record.Poll.Options)),
+		Voters:         len(record.Votes),
+	}
+	for _, option := range record.Poll.Options {
+		result.Counts[option.ID] = 0
+	}
+	for _, optionIDs := range record.Votes {
+		for _, optionID := range optionIDs {
+			result.Counts[optionID]++
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+// PresenceSettings 是用户自己设置的状态，在线时生效。
+// State 只能是 online、away、busy 或 invisible，离线由连接决定。
+type PresenceSettings struct {
+	State          types.PresenceState `json:"state"`
+	StatusText     string              `json:"statusText,omitempty"`
+	StatusExpireAt int64               `json:"statusExpireAt,omitempty"`
+}
+
+type PresenceStore struct {
+	mu sync.RWMutex
+
+	settings map[string]*PresenceSettings // 用户ID -> 设置
+}
+
+func NewPresenceStore() *PresenceStore {
+	return &PresenceStore{
+		settings: make(map[string]*PresenceSettings),
+	}
+}
+
+// Settings 返回用户的状态设置，未设置过时返回在线。
+func (s *PresenceStore) Settings(userID string) *PresenceSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if settings, ok := s.settings[userID]; ok {
+		copied := *settings
+		return &copied
+	}
+	return &PresenceSettings{State: types.PresenceOnline}
+}
+
+func (s *PresenceStore) Save(userID string, settings *PresenceSettings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *settings
+	s.settings[userID] = &copied
+}
+
+// ClearExpiredStatus 清除在 now 之前过期的自定义状态，返回受影响的用户ID。
+func (s *PresenceStore) ClearExpiredStatus(now int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var userIDs []string
+	for userID, settings := range s.settings {
+		if settings.StatusExpireAt > 0 && settings.StatusExpireAt <= now {
+			settings.StatusText = ""
+			settings.StatusExpireAt = 0
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DMPolicy 决定哪些用户可以给自己发私聊。
+type DMPolicy string
+
+const (
+	DMPolicyEveryone DMPolicy = "everyone"
+	DMPolicyContacts DMPolicy = "contacts"
+	DMPolicyNobody   DMPolicy = "nobody"
+)
+
+// PrivacySettings 是用户级别的隐私设置。
+type PrivacySettings struct {
+	DMPolicy DMPolicy `json:"dmPolicy"`
+}
+
+type PrivacyStore struct {
+	mu sync.RWMutex
+
+	settings map[string]*PrivacySettings // 用户ID -> 设置
+	blocks   map[string]map[string]int64 // 用户ID -> 被屏蔽的用户ID -> 屏蔽时间
+}
+
+// BlockedUser 是屏蔽列表中的一项。
+type BlockedUser struct {
+	UserID    string `json:"userId"`
+	BlockTime int64  `json:"blockTime"`
+}
+
+func NewPrivacyStore() *PrivacyStore {
+	return &PrivacyStore{
+		settings: make(map[string]*PrivacySettings),
+		blocks:   make(map[string]map[string]int64),
+	}
+}
+
+// Settings 返回用户的隐私设置，未设置过时返回默认值。
+func (s *PrivacyStore) Settings(userID string) *PrivacySettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if settings, ok := s.settings[userID]; ok {
+		copied := *settings
+		return &copied
+	}
+	return &PrivacySettings{DMPolicy: DMPolicyEveryone}
+}
+
+// SetDMPolicy 修改私聊权限。
+func (s *PrivacyStore) SetDMPolicy(userID string, policy DMPolicy) *PrivacySettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, ok := s.settings[userID]
+	if !ok {
+		settings = &PrivacySettings{DMPolicy: DMPolicyEveryone}
+		s.settings[userID] = settings
+	}
+	settings.DMPolicy = policy
+
+	copied := *settings
+	return &copied
+}
+
+// Block 屏蔽 targetID，重复屏蔽不会改变屏蔽时间。
+func (s *PrivacyStore) Block(userID, targetID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blocks[userID]; !ok {
+		s.blocks[userID] = make(map[string]int64)
+	}
+	if _, ok := s.blocks[userID][targetID]; !ok {
+		s.blocks[userID][targetID] = time.Now().Unix()
+	}
+}
+
+// Unblock 取消屏蔽，返回之前是否屏蔽过。
+func (s *PrivacyStore) Unblock(userID, targetID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blocks[userID][targetID]; !ok {
+		return false
+	}
+	delete(s.blocks[userID], targetID)
+	return true
+}
+
+// IsBlocked 判断 userID 是否屏蔽了 targetID。
+func (s *PrivacyStore) IsBlocked(userID, targetID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.blocks[userID][targetID]
+	return ok
+}
+
+// Blocked 返回用户的屏蔽列表，最近屏蔽的在前。
+func (s *PrivacyStore) Blocked(userID string) []*BlockedUser {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*BlockedUser, 0, len(s.blocks[userID]))
+	for targetID, blockTime := range s.blocks[userID] {
+		result = append(result, &BlockedUser{UserID: targetID, BlockTime: blockTime})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BlockTime > result[j].BlockTime
+	})
+	return result
+}
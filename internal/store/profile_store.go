@@ -0,0 +1,60 @@
+package store
+
+import (
+	"sync"
+)
+
+// Profile 是用户自己编辑的资料，DisplayName 为空时使用连接时的名称。
+type Profile struct {
+	DisplayName string `json:"displayName,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	Timezone    string `json:"timezone,omitempty"` // IANA 时区名，如 Asia/Shanghai
+	Avatar      *Blob  `json:"-"`
+	UpdateTime  int64  `json:"updateTime,omitempty"`
+}
+
+// Blob 指向 BlobStore 中的一份数据。
+type Blob struct {
+	Key  string
+	MIME string
+	Size int64
+}
+
+type ProfileStore struct {
+	mu sync.RWMutex
+
+	profiles map[string]*Profile // 用户ID -> 资料
+}
+
+func NewProfileStore() *ProfileStore {
+	return &ProfileStore{
+		profiles: make(map[string]*Profile),
+	}
+}
+
+// Get 返回用户资料，未设置过时返回空资料。
+func (s *ProfileStore) Get(userID string) *Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if profile, ok := s.profiles[userID]; ok {
+		copied := *profile
+		return &copied
+	}
+	return &Profile{}
+}
+
+// Update 在锁内修改用户资料并返回修改后的副本。
+func (s *ProfileStore) Update(userID string, fn func(profile *Profile)) *Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[userID]
+	if !ok {
+		profile = &Profile{}
+		s.profiles[userID] = profile
+	}
+	fn(profile)
+	copied := *profile
+	return &copied
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// Watermark 记录用户在某个会话中已读到的位置。
+type Watermark struct {
+	UserID         string `json:"userId"`
+	ConversationID string `json:"conversationId"`
+	MessageID      string `json:"messageId"`
+	Seq            int64  `json:"seq"`
+	UpdateTime     int64  `json:"time"`
+}
+
+// ReceiptStore 保存每个会话中每个用户的已读水位。
+type ReceiptStore struct {
+	mu sync.RWMutex
+
+	read map[string]map[string]*Watermark // 会话ID -> 用户ID -> 已读水位
+}
+
+func NewReceiptStore() *ReceiptStore {
+	return &ReceiptStore{
+		read: make(map[string]map[string]*Watermark),
+	}
+}
+
+// MarkRead 推进用户的已读水位，返回推进前的顺序号。
+// 水位只会前进，如果 seq 不大于当前水位则返回 false。
+func (s *ReceiptStore) MarkRead(conversationID, userID, messageID string, seq int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, ok := s.read[conversationID]
+	if !ok {
+		users = make(map[string]*Watermark)
+		s.read[conversationID] = users
+	}
+
+	var previous int64
+	if mark, ok := users[userID]; ok {
+		if seq <= mark.Seq {
+			return mark.Seq, false
+		}
+		previous = mark.Seq
+	}
+
+	users[userID] = &Watermark{
+		UserID:         userID,
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		Seq:            seq,
+		UpdateTime:     time.Now().Unix(),
+	}
+	return previous, true
+}
+
+// ReadWatermarks 返回会话中所有用户的已读水位。
+func (s *ReceiptStore) ReadWatermarks(conversationID string) []*Watermark {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := s.read[conversationID]
+	result := make([]*Watermark, 0, len(users))
+	for _, mark := range users {
+		copied := *mark
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// ReadSeq 返回用户在会话中的已读顺序号，未读过返回 0。
+func (s *ReceiptStore) ReadSeq(conversationID, userID string) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if mark, ok := s.read[conversationID][userID]; ok {
+		return mark.Seq
+	}
+	return 0
+}
+
+// ReadWatermark 返回用户在会话中的已读水位。
+func (s *ReceiptStore) ReadWatermark(conversationID, userID string) (*Watermark, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mark, ok := s.read[conversationID][userID]
+	if !ok {
+		return nil, false
+	}
+	copied := *mark
+	return &copied, true
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+)
+
+type ScheduleStatus int
+
+const (
+	SchedulePending   ScheduleStatus = iota // 等待发送
+	ScheduleSent                            // 已发送
+	ScheduleFailed                          // 发送失败
+	ScheduleCancelled                       // 已取消
+)
+
+// ScheduledMessage 是一条定时发送的消息。
+type ScheduledMessage struct {
+	ID         string            `json:"id"`
+	UserID     string            `json:"userId"`
+	Type       types.MessageType `json:"type"` // 2 房间 / 3 私聊 / 11 群组
+	To         string            `json:"to"`
+	Payload    *types.Payload    `json:"payload"`
+	TTL        int64             `json:"ttl,omitempty"`
+	SendAt     int64             `json:"sendAt"` // 计划发送时间
+	Status     ScheduleStatus    `json:"status"`
+	MessageID  string            `json:"messageId,omitempty"` // 发送后生成的消息ID
+	Error      string            `json:"error,omitempty"`
+	CreateTime int64             `json:"createTime"`
+	UpdateTime int64             `json:"updateTime"`
+}
+
+type ScheduleStore struct {
+	mu sync.RWMutex
+
+	schedules map[string]*ScheduledMessage // 定时消息ID -> 定时消息
+}
+
+func NewScheduleStore() *ScheduleStore {
+	return &ScheduleStore{
+		schedules: make(map[string]*ScheduledMessage),
+	}
+}
+
+func (s *ScheduleStore) Save(schedule *ScheduledMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[schedule.ID] = copySchedule(schedule)
+}
+
+func (s *ScheduleStore) Get(id string) (*ScheduledMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return nil, false
+	}
+	return copySchedule(schedule), true
+}
+
+// ListByUser 返回用户的定时消息，按计划发送时间排序。
+func (s *ScheduleStore) ListByUser(userID string) []*ScheduledMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*ScheduledMessage, 0)
+	for _, schedule := range s.schedules {
+		if schedule.UserID == userID {
+			result = append(result, copySchedule(schedule))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SendAt < result[j].SendAt
+	})
+	return result
+}
+
+// UpdatePending 在定时消息仍处于等待状态时执行 update，返回更新后的定时消息。
+// 与 ClaimDue 共用同一把锁，保证不会修改一条正在发送的消息。
+func (s *ScheduleStore) UpdatePending(id string, update func(schedule *ScheduledMessage)) (*ScheduledMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok || schedule.Status != SchedulePending {
+		return nil, false
+	}
+	update(schedule)
+	return copySchedule(schedule), true
+}
+
+// ClaimDue 取出所有到期的等待中消息，并把它们标记为已发送。
+func (s *ScheduleStore) ClaimDue(now int64) []*ScheduledMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []*ScheduledMessage
+	for _, schedule := range s.schedules {
+		if schedule.Status == SchedulePending && schedule.SendAt <= now {
+			schedule.Status = ScheduleSent
+			schedule.UpdateTime = now
+			result = append(result, copySchedule(schedule))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SendAt < result[j].SendAt
+	})
+	return result
+}
+
+// copySchedule 复制定时消息，Payload 也一并复制，调用者拿到的副本与保存的数据互不影响。
+func copySchedule(schedule *ScheduledMessage) *ScheduledMessage {
+	copied := *schedule
+	copied.Payload = schedule.Payload.Clone()
+	return &copied
+}
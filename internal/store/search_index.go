@@ -0,0 +1,239 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/l-jessie/test-im/internal/model/types"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// searchIndex 是消息文本的倒排索引，由 MessageStore 在自己的锁内维护。
+//
+// 拉丁文字等以空白分词的文本按单词建索引；中日韩文字没有分隔符，
+// 按连续字符的二元组（bigram）建索引，同时保留单字，
+// 这样任意长度的中文查询都能命中，最后再用子串匹配确认结果。
+type searchIndex struct {
+	postings map[string]map[string]bool // 词 -> 消息ID
+	texts    map[string]string          // 消息ID -> 规范化后的文本
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]bool),
+		texts:    make(map[string]string),
+	}
+}
+
+func (idx *searchIndex) add(msg *types.Message) {
+	text := normalizeText(messageText(msg))
+	if text == "" {
+		return
+	}
+
+	idx.texts[msg.ID] = text
+	for _, term := range indexTerms(text) {
+		if _, ok := idx.postings[term]; !ok {
+			idx.postings[term] = make(map[string]bool)
+		}
+		idx.postings[term][msg.ID] = true
+	}
+}
+
+func (idx *searchIndex) remove(msgID string) {
+	text, ok := idx.texts[msgID]
+	if !ok {
+		return
+	}
+
+	delete(idx.texts, msgID)
+	for _, term := range indexTerms(text) {
+		delete(idx.postings[term], msgID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+}
+
+// search 返回包含查询中所有词的消息ID。
+func (idx *searchIndex) search(query string) []string {
+	query = normalizeText(query)
+	segments := querySegments(query)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var candidates map[string]bool
+	for _, segment := range segments {
+		for _, term := range queryTerms(segment) {
+			candidates = intersect(candidates, idx.postings[term])
+			if len(candidates) == 0 {
+				return nil
+			}
+		}
+	}
+
+	// 索引只能保证词都出现，这里确认每个查询片段都是原文的子串
+	result := make([]string, 0, len(candidates))
+	for msgID := range candidates {
+		text := idx.texts[msgID]
+		matched := true
+		for _, segment := range segments {
+			if !strings.Contains(text, segment) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, msgID)
+		}
+	}
+	return result
+}
+
+func intersect(current map[string]bool, postings map[string]bool) map[string]bool {
+	if current == nil {
+		result := make(map[string]bool, len(postings))
+		for msgID := range postings {
+			result[msgID] = true
+		}
+		return result
+	}
+	for msgID := range current {
+		if !postings[msgID] {
+			delete(current, msgID)
+		}
+	}
+	return current
+}
+
+// messageText 返回消息中可以搜索的文本。
+func messageText(msg *types.Message) string {
+	if msg.Payload == nil {
+		return ""
+	}
+
+	switch msg.Payload.Type {
+	case types.PayloadTypeText, types.PayloadTypeMarkdown:
+		var text string
+		if err := json.Unmarshal(msg.Payload.Content, &text); err == nil {
+			return text
+		}
+	case types.PayloadTypePoll:
+		var poll types.Poll
+		if err := json.Unmarshal(msg.Payload.Content, &poll); err == nil {
+			return poll.Question
+		}
+	case types.PayloadTypeImage, types.PayloadTypeFile:
+		if msg.Payload.FileMeta != nil {
+			return msg.Payload.FileMeta.Name
+		}
+	}
+	return ""
+}
+
+// normalizeText 统一全角/半角和大小写。
+func normalizeText(text string) string {
+	return strings.ToLower(norm.NFKC.String(text))
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// splitRuns 把文本切分为连续的中日韩字符段和其它文字的单词。
+func splitRuns(text string) (words []string, cjkRuns [][]rune) {
+	var word []rune
+	var run []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		if len(run) > 0 {
+			cjkRuns = append(cjkRuns, run)
+			run = nil
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			run = append(run, r)
+		case isWordRune(r):
+			if len(run) > 0 {
+				cjkRuns = append(cjkRuns, run)
+				run = nil
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words, cjkRuns
+}
+
+// indexTerms 返回文本需要写入索引的词：单词、中日韩单字和二元组。
+func indexTerms(text string) []string {
+	words, cjkRuns := splitRuns(text)
+
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for _, word := range words {
+		add(word)
+	}
+	for _, run := range cjkRuns {
+		for i := range run {
+			add(string(run[i]))
+			if i+1 < len(run) {
+				add(string(run[i : i+2]))
+			}
+		}
+	}
+	return terms
+}
+
+// querySegments 把查询切分为需要同时出现的片段。
+func querySegments(query string) []string {
+	words, cjkRuns := splitRuns(query)
+	segments := words
+	for _, run := range cjkRuns {
+		segments = append(segments, string(run))
+	}
+	return segments
+}
+
+// queryTerms 返回查询片段对应的索引词，中日韩片段使用二元组，单字使用单字。
+func queryTerms(segment string) []string {
+	runes := []rune(segment)
+	if len(runes) == 0 || !isCJK(runes[0]) {
+		return []string{segment}
+	}
+	if len(runes) == 1 {
+		return []string{segment}
+	}
+
+	terms := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		terms = append(terms, string(runes[i:i+2]))
+	}
+	return terms
+}
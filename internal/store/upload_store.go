@@ -0,0 +1,76 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// UploadSession 是一次可续传的分块上传。
+type UploadSession struct {
+	mu sync.Mutex // 保证同一个上传同时只写入一个分块
+
+	ID             string
+	UploaderID     string
+	ConversationID string
+	Name           string
+	Size           int64  // 文件总字节数
+	Offset         int64  // 已接收的字节数
+	Checksum       string // 整个文件的 sha256 十六进制值，为空时不校验
+	PartPath       string // 已接收数据的临时文件
+	CreateTime     time.Time
+	UpdateTime     time.Time
+}
+
+func (u *UploadSession) Lock() {
+	u.mu.Lock()
+}
+
+func (u *UploadSession) Unlock() {
+	u.mu.Unlock()
+}
+
+type UploadStore struct {
+	mu sync.RWMutex
+
+	sessions map[string]*UploadSession // 上传ID -> 上传会话
+}
+
+func NewUploadStore() *UploadStore {
+	return &UploadStore{
+		sessions: make(map[string]*UploadSession),
+	}
+}
+
+func (s *UploadStore) Save(session *UploadSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = session
+}
+
+func (s *UploadStore) Get(id string) (*UploadSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+func (s *UploadStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+}
+
+// List 返回所有上传会话。会话的字段由会话自己的锁保护，调用者需要加锁后再读取。
+func (s *UploadStore) List() []*UploadSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*UploadSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		result = append(result, session)
+	}
+	return result
+}
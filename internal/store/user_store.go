@@ -0,0 +1,77 @@
+package store
+
+import (
+	"sync"
+)
+
+// UserRecord 是用户目录中的一项，用户第一次连接时创建，断开后仍然保留。
+type UserRecord struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	FirstSeen int64  `json:"firstSeen"`
+	LastSeen  int64  `json:"lastSeen"` // 最后一个设备断开的时间
+}
+
+type UserStore struct {
+	mu sync.RWMutex
+
+	users map[string]*UserRecord // 用户ID -> 用户
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users: make(map[string]*UserRecord),
+	}
+}
+
+// Touch 记录用户连接，用户不存在时创建，名称不为空时更新名称。
+func (s *UserStore) Touch(userID, name string, now int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		user = &UserRecord{ID: userID, FirstSeen: now}
+		s.users[userID] = user
+	}
+	if name != "" {
+		user.Name = name
+	}
+}
+
+// SetLastSeen 记录用户最后在线的时间。连接和断开的通知可能乱序到达，用户不存在时同样创建。
+func (s *UserStore) SetLastSeen(userID string, lastSeen int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		user = &UserRecord{ID: userID, FirstSeen: lastSeen}
+		s.users[userID] = user
+	}
+	user.LastSeen = lastSeen
+}
+
+func (s *UserStore) Get(userID string) (*UserRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, false
+	}
+	copied := *user
+	return &copied, true
+}
+
+func (s *UserStore) List() []*UserRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*UserRecord, 0, len(s.users))
+	for _, user := range s.users {
+		copied := *user
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -20,9 +20,19 @@ const ChatService = {
     return deviceId;
   },
   
+  // --- Session ---
+  // All REST calls except login identify the user by the device session token
+  setSession(session) {
+    if (session) {
+      axios.defaults.headers.common['X-Session-Token'] = session;
+    } else {
+      delete axios.defaults.headers.common['X-Session-Token'];
+    }
+  },
+
   // --- API Calls ---
-  async login(username) {
-    const response = await axios.post(`${API_BASE_URL}/login`, { username });
+  async login(username, deviceId) {
+    const response = await axios.post(`${API_BASE_URL}/login`, { username, deviceId });
     return response.data.data;
   },
   
@@ -36,13 +46,13 @@ const ChatService = {
     return response.data.data;
   },
 
-  async createRoom({ name, password, userId }) {
-    const response = await axios.post(`${API_BASE_URL}/rooms`, { name, password, userId });
+  async createRoom({ name, password }) {
+    const response = await axios.post(`${API_BASE_URL}/rooms`, { name, password });
     return response.data.data;
   },
 
-  async joinRoom({ roomId, userId, deviceId, password }) {
-    const response = await axios.post(`${API_BASE_URL}/rooms/${roomId}/join`, { userId, deviceId: deviceId, roomId, password });
+  async joinRoom({ roomId, password }) {
+    const response = await axios.post(`${API_BASE_URL}/rooms/${roomId}/join`, { roomId, password });
     return response.data;
   },
 
@@ -52,13 +62,13 @@ const ChatService = {
   },
 
   // --- WebSocket Management ---
-  connect(userId, deviceId, username, { onOpen, onMessage, onClose, onError }) {
+  connect(userId, deviceId, session, username, { onOpen, onMessage, onClose, onError }) {
     if (socket && socket.readyState === WebSocket.OPEN) {
       console.log('WebSocket is already connected.');
       return;
     }
 
-    const url = `${WS_BASE_URL}?token=${userId}&deviceId=${deviceId}&username=${encodeURIComponent(username)}`;
+    const url = `${WS_BASE_URL}?token=${userId}&deviceId=${deviceId}&session=${session}&username=${encodeURIComponent(username)}`;
     socket = new WebSocket(url);
 
     socket.onopen = onOpen;
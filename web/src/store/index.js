@@ -68,8 +68,8 @@ export const useChatStore = defineStore('chat', {
         // --- Authentication and Connection ---
         async login(username) {
             try {
-                const userData = await ChatService.login(username);
-                this.user = {id: userData.id, name: userData.username};
+                const userData = await ChatService.login(username, ChatService.getDeviceId());
+                this.user = {id: userData.id, name: userData.username, session: userData.session};
                 this._addUserToMap(this.user); // Add self to map
                 sessionStorage.setItem(USER_SESSION_KEY, JSON.stringify(this.user));
                 this.connectWebSocket();
@@ -83,14 +83,16 @@ export const useChatStore = defineStore('chat', {
 
         logout() {
             ChatService.disconnect();
+            ChatService.setSession(null);
             this.$reset(); // Use pinia's $reset to go back to initial state
             sessionStorage.removeItem(USER_SESSION_KEY);
         },
 
         connectWebSocket() {
-            if (!this.user?.id || !this.user.name) return;
+            if (!this.user?.id || !this.user.name || !this.user.session) return;
+            ChatService.setSession(this.user.session);
 
-            ChatService.connect(this.user.id, ChatService.getDeviceId(), this.user.name, {
+            ChatService.connect(this.user.id, ChatService.getDeviceId(), this.user.session, this.user.name, {
                 onOpen: () => {
                     this.isConnected = true;
                     this.fetchRooms();
@@ -163,7 +165,13 @@ export const useChatStore = defineStore('chat', {
         checkExistingSession() {
             const savedUser = sessionStorage.getItem(USER_SESSION_KEY);
             if (savedUser) {
-                this.user = JSON.parse(savedUser);
+                const user = JSON.parse(savedUser);
+                if (!user.session) {
+                    // Sessions saved before device credentials were issued must log in again
+                    sessionStorage.removeItem(USER_SESSION_KEY);
+                    return;
+                }
+                this.user = user;
                 this._addUserToMap(this.user); // Add self to map on session restore
                 this.connectWebSocket();
             }
@@ -240,7 +248,7 @@ export const useChatStore = defineStore('chat', {
         async createNewRoom({name, password}) {
             if (!this.user?.id) return;
             try {
-                const newRoom = await ChatService.createRoom({name, password, userId: this.user.id});
+                const newRoom = await ChatService.createRoom({name, password});
                 this.fetchRooms(); // Refetch all rooms
                 // Optionally, if newRoom contains owner details, add to map
                 if (newRoom.userId && newRoom.userName) {
@@ -273,8 +281,7 @@ export const useChatStore = defineStore('chat', {
         async joinRoom(roomId, password = '') {
             if (!this.user?.id) return;
             try {
-                const deviceId = ChatService.getDeviceId();
-                await ChatService.joinRoom({roomId, userId: this.user.id, deviceId, password});
+                await ChatService.joinRoom({roomId, password});
                 console.log(`Successfully joined room ${roomId}`);
             } catch (error) {
                 console.error(`Failed to join room ${roomId}:`, error);